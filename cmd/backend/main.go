@@ -18,6 +18,18 @@ func run(conf *config.Config) error {
 		server.WithEnvironment(conf.Env),
 		server.WithLogger(conf.Logger),
 		server.WithDbPath(conf.DbPath),
+		server.WithCacheSize(conf.CacheSize),
+		server.WithCacheTTL(conf.CacheTTL),
+		server.WithSeed(conf.Seed),
+		server.WithSeedFile(conf.SeedFile),
+		server.WithAuth(conf.AuthEnabled),
+		server.WithAdminKey(conf.AdminAPIKey),
+		server.WithJWTKeysFile(conf.JWTKeysFile),
+		server.WithJWTAudience(conf.JWTAudience),
+		server.WithRateLimitCalculate(conf.RateLimitCalculate),
+		server.WithRateLimitMutate(conf.RateLimitMutate),
+		server.WithRateLimitPersist(conf.RateLimitPersist),
+		server.WithIdempotencyTTL(conf.IdempotencyTTL),
 	}
 
 	s := server.NewServer(serverOptions...)
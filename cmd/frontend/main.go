@@ -21,8 +21,11 @@ func serveHTML(w http.ResponseWriter, r *http.Request) {
 		backendAPI = "http://localhost:8080/v1"
 	}
 
+	// The key is served in the page, so it must only grant the packs:read and
+	// order:calculate scopes. Changes are made with a key the operator types in.
 	err = tmpl.Execute(w, map[string]string{
-		"BackendAPI": backendAPI,
+		"BackendAPI":    backendAPI,
+		"BackendAPIKey": os.Getenv("BACKEND_API_KEY"),
 	})
 	if err != nil {
 		http.Error(w, "Failed to execute template", http.StatusInternalServerError)
@@ -1,6 +1,8 @@
 package server
 
 import (
+	"time"
+
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 )
 
@@ -27,3 +29,75 @@ func WithLogger(v logger.Logger) ServerOption {
 		s.logger = v
 	}
 }
+
+func WithCacheSize(v int) ServerOption {
+	return func(s *Server) {
+		s.cacheSize = v
+	}
+}
+
+func WithCacheTTL(v time.Duration) ServerOption {
+	return func(s *Server) {
+		s.cacheTTL = v
+	}
+}
+
+func WithSeed(v string) ServerOption {
+	return func(s *Server) {
+		s.seed = v
+	}
+}
+
+func WithSeedFile(v string) ServerOption {
+	return func(s *Server) {
+		s.seedFile = v
+	}
+}
+
+func WithAuth(v bool) ServerOption {
+	return func(s *Server) {
+		s.authEnabled = v
+	}
+}
+
+func WithAdminKey(v string) ServerOption {
+	return func(s *Server) {
+		s.adminKey = v
+	}
+}
+
+func WithJWTKeysFile(v string) ServerOption {
+	return func(s *Server) {
+		s.jwtKeysFile = v
+	}
+}
+
+func WithJWTAudience(v string) ServerOption {
+	return func(s *Server) {
+		s.jwtAudience = v
+	}
+}
+
+func WithRateLimitCalculate(v string) ServerOption {
+	return func(s *Server) {
+		s.rateLimitCalculate = v
+	}
+}
+
+func WithRateLimitMutate(v string) ServerOption {
+	return func(s *Server) {
+		s.rateLimitMutate = v
+	}
+}
+
+func WithRateLimitPersist(v bool) ServerOption {
+	return func(s *Server) {
+		s.rateLimitPersist = v
+	}
+}
+
+func WithIdempotencyTTL(v time.Duration) ServerOption {
+	return func(s *Server) {
+		s.idempotencyTTL = v
+	}
+}
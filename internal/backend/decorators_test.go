@@ -2,6 +2,7 @@ package server
 
 import (
 	"testing"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/stretchr/testify/assert"
@@ -36,3 +37,87 @@ func TestWithLogger(t *testing.T) {
 	opt(s)
 	assert.Equal(t, mockLogger, s.logger)
 }
+
+func TestWithCacheSize(t *testing.T) {
+	s := &Server{}
+	opt := WithCacheSize(10)
+	opt(s)
+	assert.Equal(t, 10, s.cacheSize)
+}
+
+func TestWithCacheTTL(t *testing.T) {
+	s := &Server{}
+	opt := WithCacheTTL(time.Minute)
+	opt(s)
+	assert.Equal(t, time.Minute, s.cacheTTL)
+}
+
+func TestWithSeed(t *testing.T) {
+	s := &Server{}
+	opt := WithSeed("none")
+	opt(s)
+	assert.Equal(t, "none", s.seed)
+}
+
+func TestWithSeedFile(t *testing.T) {
+	s := &Server{}
+	opt := WithSeedFile("seed.csv")
+	opt(s)
+	assert.Equal(t, "seed.csv", s.seedFile)
+}
+
+func TestWithAuth(t *testing.T) {
+	s := &Server{}
+	opt := WithAuth(true)
+	opt(s)
+	assert.True(t, s.authEnabled)
+}
+
+func TestWithAdminKey(t *testing.T) {
+	s := &Server{}
+	opt := WithAdminKey("secret")
+	opt(s)
+	assert.Equal(t, "secret", s.adminKey)
+}
+
+func TestWithJWTKeysFile(t *testing.T) {
+	s := &Server{}
+	opt := WithJWTKeysFile("/etc/packs/jwks.json")
+	opt(s)
+	assert.Equal(t, "/etc/packs/jwks.json", s.jwtKeysFile)
+}
+
+func TestWithJWTAudience(t *testing.T) {
+	s := &Server{}
+	opt := WithJWTAudience("packs")
+	opt(s)
+	assert.Equal(t, "packs", s.jwtAudience)
+}
+
+func TestWithRateLimitCalculate(t *testing.T) {
+	s := &Server{}
+	opt := WithRateLimitCalculate("300/1m")
+	opt(s)
+	assert.Equal(t, "300/1m", s.rateLimitCalculate)
+}
+
+func TestWithRateLimitMutate(t *testing.T) {
+	s := &Server{}
+	opt := WithRateLimitMutate("60/1m")
+	opt(s)
+	assert.Equal(t, "60/1m", s.rateLimitMutate)
+}
+
+func TestWithRateLimitPersist(t *testing.T) {
+	s := &Server{}
+	opt := WithRateLimitPersist(true)
+	opt(s)
+	assert.True(t, s.rateLimitPersist)
+}
+
+func TestWithIdempotencyTTL(t *testing.T) {
+	s := &Server{}
+	opt := WithIdempotencyTTL(time.Hour)
+	opt(s)
+	assert.Equal(t, time.Hour, s.idempotencyTTL)
+}
@@ -2,18 +2,25 @@ package server
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
+	"strings"
 	"sync"
 	"syscall"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/internal/handler"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/syndtr/goleveldb/leveldb"
 )
 
 type Server struct {
@@ -21,6 +28,20 @@ type Server struct {
 	port        string
 	dbPath      string
 	logger      logger.Logger
+	cacheSize   int
+	cacheTTL    time.Duration
+	seed        string
+	seedFile    string
+	authEnabled bool
+	adminKey    string
+	jwtKeysFile string
+	jwtAudience string
+
+	rateLimitCalculate string
+	rateLimitMutate    string
+	rateLimitPersist   bool
+
+	idempotencyTTL time.Duration
 }
 
 type ServerOption func(*Server)
@@ -32,23 +53,148 @@ const (
 )
 
 func NewServer(options ...ServerOption) *Server {
-	svr := &Server{}
+	svr := &Server{
+		cacheSize: optimizer.DefaultCacheSize,
+		cacheTTL:  optimizer.DefaultCacheTTL,
+	}
 	for _, opt := range options {
 		opt(svr)
 	}
 	return svr
 }
 
+// seedOption returns the sizer option seeding the default catalog from the seed file if
+// one is set, or else from the listed seed sizes. A seed of "none" starts it empty, and
+// no seed at all with the default sizes.
+func (s *Server) seedOption() (sizer.Option, error) {
+	var (
+		packs []sizer.Pack
+		err   error
+	)
+	switch {
+	case s.seedFile != "":
+		packs, err = sizer.ReadSeedFile(s.seedFile)
+	case strings.EqualFold(s.seed, "none"):
+		return sizer.WithoutSeed(), nil
+	case s.seed != "":
+		packs, err = sizer.ParseSeed(s.seed)
+	default:
+		return func(*sizer.Sizer) {}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return sizer.WithSeed(packs), nil
+}
+
+// authOptions returns the handler options enabling API keys kept in keys, if enabled,
+// after storing the configured admin key in it. Bearer tokens are accepted as well when
+// a file of JWT verification keys is configured. It fails when no client could
+// authenticate: without an admin key, bearer tokens or any API key already stored.
+func (s *Server) authOptions(keys *auth.KeyStore) ([]handler.Option, error) {
+	if !s.authEnabled {
+		s.logger.Warn("API key authentication is disabled, every client has full access")
+		return nil, nil
+	}
+
+	if s.adminKey != "" {
+		if err := keys.Ensure(s.adminKey, "admin", []auth.Scope{auth.ScopeAdmin}); err != nil {
+			return nil, err
+		}
+	} else if s.jwtKeysFile == "" {
+		active, err := hasActiveKey(keys)
+		if err != nil {
+			return nil, err
+		}
+		if !active {
+			return nil, errors.New("no API key is stored and no admin key is set, so no client could authenticate")
+		}
+	}
+	s.logger.Info("API key authentication enabled")
+	options := []handler.Option{handler.WithKeyStore(keys)}
+
+	if s.jwtKeysFile != "" {
+		if s.jwtAudience == "" {
+			return nil, errors.New("an audience is required to verify bearer tokens")
+		}
+		jwtKeys, err := auth.ReadJWTKeys(s.jwtKeysFile)
+		if err != nil {
+			return nil, err
+		}
+		s.logger.Info("JWT bearer authentication enabled")
+		options = append(options, handler.WithTokenVerifier(auth.NewJWTVerifier(jwtKeys, s.jwtAudience)))
+	}
+	return options, nil
+}
+
+// hasActiveKey reports whether keys holds an API key that was not revoked.
+func hasActiveKey(keys *auth.KeyStore) (bool, error) {
+	list, err := keys.List()
+	if err != nil {
+		return false, err
+	}
+	for _, key := range list {
+		if key.RevokedAt == nil {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// rateLimitOption returns the handler option limiting the requests of each client to
+// the configured budgets, kept in db when persisted.
+func (s *Server) rateLimitOption(db *leveldb.DB) (handler.Option, error) {
+	calculate, err := ratelimit.ParseLimit(s.rateLimitCalculate)
+	if err != nil {
+		return nil, err
+	}
+	mutate, err := ratelimit.ParseLimit(s.rateLimitMutate)
+	if err != nil {
+		return nil, err
+	}
+
+	var options []ratelimit.Option
+	if s.rateLimitPersist {
+		options = append(options, ratelimit.WithStore(db))
+	}
+	s.logger.Info(fmt.Sprintf("rate limits per client: %s calculate, %s mutate requests", calculate, mutate))
+	limiter := ratelimit.NewLimiter(map[ratelimit.Budget]ratelimit.Limit{
+		ratelimit.BudgetCalculate: calculate,
+		ratelimit.BudgetMutate:    mutate,
+	}, options...)
+	return handler.WithRateLimiter(limiter), nil
+}
+
 // Start starts the baceknd server
 func (s *Server) Start(ctx context.Context) {
-	sz, err := sizer.NewSizer(s.dbPath, s.logger)
+	seed, err := s.seedOption()
+	if err != nil {
+		log.Fatalf("Invalid seed catalog: %v", err)
+	}
+
+	sz, err := sizer.NewSizer(s.dbPath, s.logger, seed)
 	if err != nil {
 		log.Fatalf("Failed to open LevelDB: %v", err)
 	}
 	defer sz.Close()
 
-	op := optimizer.New(sz, s.logger)
-	h := handler.New(op)
+	catalogs := optimizer.NewRegistry(sz, s.logger,
+		optimizer.WithCacheSize(s.cacheSize),
+		optimizer.WithCacheTTL(s.cacheTTL),
+	)
+	handlerOptions, err := s.authOptions(auth.NewKeyStore(sz.DB()))
+	if err != nil {
+		log.Fatalf("Failed to set up authentication: %v", err)
+	}
+	rateLimit, err := s.rateLimitOption(sz.DB())
+	if err != nil {
+		log.Fatalf("Invalid rate limit: %v", err)
+	}
+	handlerOptions = append(handlerOptions, rateLimit)
+	if s.idempotencyTTL > 0 {
+		handlerOptions = append(handlerOptions, handler.WithIdempotencyStore(idempotency.NewStore(sz.DB(), s.idempotencyTTL)))
+	}
+	h := handler.New(catalogs, handlerOptions...)
 
 	r, err := handler.NewRouter(h)
 	if err != nil {
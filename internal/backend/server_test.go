@@ -5,13 +5,18 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"os"
+	"path/filepath"
 	"testing"
 	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/internal/handler"
 	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 	"github.com/stretchr/testify/assert"
+	"github.com/syndtr/goleveldb/leveldb"
 	"go.uber.org/zap"
 )
 
@@ -26,7 +31,7 @@ func TestNewServerBackend(t *testing.T) {
 	dir, cleanup := setupTempDB(t)
 	defer cleanup()
 
-	mockOptimizer := &mocks.MockOptimizerInterface{}
+	mockRegistry := &mocks.MockRegistryInterface{}
 	mockLogger := logger.New(zap.DebugLevel)
 	server := NewServer(
 		func(s *Server) {
@@ -43,7 +48,7 @@ func TestNewServerBackend(t *testing.T) {
 	assert.NoError(t, err)
 
 	rr := httptest.NewRecorder()
-	handler := http.HandlerFunc(handler.New(mockOptimizer).HealthHandler)
+	handler := http.HandlerFunc(handler.New(mockRegistry).HealthHandler)
 	handler.ServeHTTP(rr, req)
 
 	assert.Equal(t, http.StatusOK, rr.Code)
@@ -70,3 +75,90 @@ func TestStart(t *testing.T) {
 	go server.Start(ctx)
 	time.Sleep(time.Microsecond)
 }
+
+func TestSeedOption(t *testing.T) {
+	l := logger.New(zap.DebugLevel)
+	seeded := func(server *Server) []int {
+		dir, cleanup := setupTempDB(t)
+		defer cleanup()
+
+		seed, err := server.seedOption()
+		assert.NoError(t, err)
+		sz, err := sizer.NewSizer(dir, l, seed)
+		assert.NoError(t, err)
+		defer sz.Close()
+
+		sizes, err := sz.GetAllSizes(sizer.DefaultCatalog)
+		assert.NoError(t, err)
+		return sizes
+	}
+
+	assert.Equal(t, []int{5000, 2000, 1000, 500, 250}, seeded(NewServer()))
+	assert.Equal(t, []int{80, 40}, seeded(NewServer(WithSeed("40,80"))))
+	assert.Empty(t, seeded(NewServer(WithSeed("none"))))
+
+	path := filepath.Join(t.TempDir(), "seed.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`[{"size": 12, "price": 1}]`), 0o600))
+	assert.Equal(t, []int{12}, seeded(NewServer(WithSeed("none"), WithSeedFile(path))))
+
+	_, err := NewServer(WithSeed("40,x")).seedOption()
+	assert.Error(t, err)
+	_, err = NewServer(WithSeedFile("seed.txt")).seedOption()
+	assert.Error(t, err)
+}
+
+func TestAuthOptions(t *testing.T) {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	keys := auth.NewKeyStore(db)
+	l := logger.New(zap.DebugLevel)
+
+	options, err := NewServer(WithLogger(l)).authOptions(keys)
+	assert.NoError(t, err)
+	assert.Empty(t, options)
+
+	// Without an admin key, tokens or a stored key nobody could authenticate.
+	_, err = NewServer(WithLogger(l), WithAuth(true)).authOptions(keys)
+	assert.Error(t, err)
+
+	options, err = NewServer(WithLogger(l), WithAuth(true), WithAdminKey("secret")).authOptions(keys)
+	assert.NoError(t, err)
+	assert.Len(t, options, 1)
+
+	options, err = NewServer(WithLogger(l), WithAuth(true)).authOptions(keys)
+	assert.NoError(t, err)
+	assert.Len(t, options, 1)
+
+	key, err := keys.Authenticate("secret")
+	assert.NoError(t, err)
+	assert.Equal(t, []auth.Scope{auth.ScopeAdmin}, key.Scopes)
+
+	secretFile := filepath.Join(t.TempDir(), "jwt.key")
+	assert.NoError(t, os.WriteFile(secretFile, []byte("0123456789abcdef0123456789abcdef"), 0o600))
+
+	options, err = NewServer(WithLogger(l), WithAuth(true), WithJWTKeysFile(secretFile), WithJWTAudience("packs")).authOptions(keys)
+	assert.NoError(t, err)
+	assert.Len(t, options, 2)
+
+	_, err = NewServer(WithLogger(l), WithAuth(true), WithJWTKeysFile(secretFile)).authOptions(keys)
+	assert.Error(t, err)
+	_, err = NewServer(WithLogger(l), WithAuth(true), WithJWTKeysFile(filepath.Join(t.TempDir(), "missing")), WithJWTAudience("packs")).authOptions(keys)
+	assert.Error(t, err)
+}
+
+func TestRateLimitOption(t *testing.T) {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	assert.NoError(t, err)
+	defer db.Close()
+	l := logger.New(zap.DebugLevel)
+
+	option, err := NewServer(WithLogger(l), WithRateLimitCalculate("300/1m"), WithRateLimitMutate("none"), WithRateLimitPersist(true)).rateLimitOption(db)
+	assert.NoError(t, err)
+	assert.NotNil(t, option)
+
+	_, err = NewServer(WithLogger(l), WithRateLimitCalculate("fast")).rateLimitOption(db)
+	assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+	_, err = NewServer(WithLogger(l), WithRateLimitMutate("0/1m")).rateLimitOption(db)
+	assert.ErrorIs(t, err, ratelimit.ErrInvalidLimit)
+}
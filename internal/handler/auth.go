@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"path"
+	"strings"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+)
+
+// apiKeyHeader is the request header carrying the API key of a client.
+const apiKeyHeader = "X-API-Key"
+
+// bearerPrefix starts the Authorization header carrying a bearer token.
+const bearerPrefix = "Bearer "
+
+// errMissingCredentials is returned by authenticate for a request without an API key nor
+// a bearer token.
+var errMissingCredentials = errors.New("missing API key or bearer token")
+
+// WithKeyStore enables authentication with the API keys of keys. Without it every
+// request is allowed.
+func WithKeyStore(keys auth.KeyStoreInterface) Option {
+	return func(h *Handler) {
+		h.keys = keys
+	}
+}
+
+// WithTokenVerifier enables authentication with the bearer tokens tokens verifies,
+// alongside API keys if enabled as well.
+func WithTokenVerifier(tokens auth.TokenVerifierInterface) Option {
+	return func(h *Handler) {
+		h.tokens = tokens
+	}
+}
+
+// Authenticate is the middleware identifying the client behind each request from its
+// bearer token or its API key, and attaching it to the request context as an
+// auth.Principal. Requests without valid credentials are answered with 401.
+func (h *Handler) Authenticate(next http.Handler) http.Handler {
+	if !h.authEnabled() {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, err := h.authenticate(r)
+		if errors.Is(err, errMissingCredentials) || errors.Is(err, auth.ErrInvalidKey) || errors.Is(err, auth.ErrInvalidToken) {
+			h.writeUnauthorized(w, r, err.Error())
+			return
+		}
+		if err != nil {
+			writeError(w, r, err, "Failed to check the credentials")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithPrincipal(r.Context(), principal)))
+	})
+}
+
+// authenticate returns the client behind r. Its subject tells how it authenticated:
+// "jwt:" followed by the subject of its token, or "apikey:" followed by the ID of its key.
+func (h *Handler) authenticate(r *http.Request) (auth.Principal, error) {
+	header := r.Header.Get("Authorization")
+	if h.tokens != nil && len(header) > len(bearerPrefix) && strings.EqualFold(header[:len(bearerPrefix)], bearerPrefix) {
+		principal, err := h.tokens.Verify(strings.TrimSpace(header[len(bearerPrefix):]))
+		principal.Subject = "jwt:" + principal.Subject
+		return principal, err
+	}
+
+	if token := r.Header.Get(apiKeyHeader); h.keys != nil && token != "" {
+		key, err := h.keys.Authenticate(token)
+		return auth.Principal{Subject: "apikey:" + key.ID, Scopes: key.Scopes}, err
+	}
+	return auth.Principal{}, errMissingCredentials
+}
+
+// Require returns the middleware answering with 403 the requests whose client was not
+// granted scope. It must run after Authenticate.
+func (h *Handler) Require(scope auth.Scope) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if !h.authEnabled() {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			principal, ok := auth.PrincipalFrom(r.Context())
+			if !ok {
+				h.writeUnauthorized(w, r, errMissingCredentials.Error())
+				return
+			}
+			if !principal.Has(scope) {
+				writeProblem(w, r, newProblem(CodeForbidden, "the "+string(scope)+" scope is required"))
+				return
+			}
+			next.ServeHTTP(w, r)
+		})
+	}
+}
+
+// ListKeys handles GET /v1/admin/keys
+// Returns every API key, revoked ones included, without the keys themselves.
+func (h *Handler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	if !h.keysEnabled(w, r) {
+		return
+	}
+
+	keys, err := h.keys.List()
+	if err != nil {
+		writeError(w, r, err, "Failed to list API keys")
+		return
+	}
+
+	response := Response{
+		Data: keys,
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// CreateKey handles POST /v1/admin/keys
+// Creates an API key with the name and scopes given in the body. The key itself is only
+// returned in this response.
+func (h *Handler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	if !h.keysEnabled(w, r) {
+		return
+	}
+
+	var req struct {
+		Name   string   `json:"name"`
+		Scopes []string `json:"scopes"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, bodyProblem(err))
+		return
+	}
+	if strings.TrimSpace(req.Name) == "" {
+		writeProblem(w, r, invalidField("name", "name is required"))
+		return
+	}
+	scopes, err := auth.ParseScopes(req.Scopes)
+	if err != nil {
+		writeError(w, r, err, "Invalid scopes")
+		return
+	}
+
+	key, token, err := h.keys.Create(req.Name, scopes)
+	if err != nil {
+		writeError(w, r, err, "Failed to create API key")
+		return
+	}
+
+	response := Response{
+		Data: struct {
+			auth.APIKey
+			Key string `json:"key"`
+		}{key, token},
+	}
+	writeJSONResponse(w, http.StatusCreated, response)
+}
+
+// RevokeKey handles DELETE /v1/admin/keys/{key}
+// Revokes an API key by ID. Its record is kept and listed as revoked.
+func (h *Handler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if !h.keysEnabled(w, r) {
+		return
+	}
+
+	if err := h.keys.Revoke(path.Base(r.URL.Path)); err != nil {
+		writeError(w, r, err, "Failed to revoke API key")
+		return
+	}
+
+	response := Response{}
+	writeJSONResponse(w, http.StatusNoContent, response)
+}
+
+// authEnabled reports whether clients must authenticate, with API keys or bearer tokens.
+func (h *Handler) authEnabled() bool {
+	return h.keys != nil || h.tokens != nil
+}
+
+// keysEnabled reports whether API keys are enabled, answering the request itself when
+// they are not.
+func (h *Handler) keysEnabled(w http.ResponseWriter, r *http.Request) bool {
+	if h.keys == nil {
+		writeProblem(w, r, newProblem(CodeNotFound, "API keys are not enabled"))
+		return false
+	}
+	return true
+}
+
+// writeUnauthorized answers r with 401, challenging the client for the credentials h
+// accepts.
+func (h *Handler) writeUnauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	if h.tokens != nil {
+		w.Header().Add("WWW-Authenticate", `Bearer`)
+	}
+	if h.keys != nil {
+		w.Header().Add("WWW-Authenticate", `APIKey header="`+apiKeyHeader+`"`)
+	}
+	writeProblem(w, r, newProblem(CodeUnauthorized, detail))
+}
@@ -0,0 +1,201 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{sizer.NewPack(250)}, nil).AnyTimes()
+	mockOptimizer.EXPECT().Calculate(250).Return(&optimizer.OptimizationResult{PacksUsed: []int{250}}, nil).AnyTimes()
+	mockOptimizer.EXPECT().RemoveSize(250).Return(nil).AnyTimes()
+
+	keys := mocks.NewMockKeyStoreInterface(ctrl)
+	keys.EXPECT().Authenticate("reader").Return(auth.APIKey{ID: "r", Scopes: []auth.Scope{auth.ScopePacksRead, auth.ScopeOrderCalculate}}, nil).AnyTimes()
+	keys.EXPECT().Authenticate("writer").Return(auth.APIKey{ID: "w", Scopes: []auth.Scope{auth.ScopePacksWrite}}, nil).AnyTimes()
+	keys.EXPECT().Authenticate("admin").Return(auth.APIKey{ID: "a", Scopes: []auth.Scope{auth.ScopeAdmin}}, nil).AnyTimes()
+	keys.EXPECT().Authenticate("revoked").Return(auth.APIKey{}, auth.ErrInvalidKey).AnyTimes()
+	keys.EXPECT().List().Return([]auth.APIKey{}, nil).AnyTimes()
+
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(mockOptimizer, nil).AnyTimes()
+
+	router, err := NewRouter(New(registry, WithKeyStore(keys)))
+	require.NoError(t, err)
+
+	tests := []struct {
+		method, path, body, key string
+		status                  int
+	}{
+		{method: "GET", path: "/health", status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/", status: http.StatusUnauthorized},
+		{method: "GET", path: "/v1/packs/", key: "revoked", status: http.StatusUnauthorized},
+		{method: "GET", path: "/v1/packs/", key: "reader", status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/", key: "writer", status: http.StatusForbidden},
+		{method: "POST", path: "/v1/order", body: `{"items_ordered": 250}`, key: "reader", status: http.StatusOK},
+		{method: "POST", path: "/v1/order", body: `{"items_ordered": 250}`, key: "writer", status: http.StatusForbidden},
+		{method: "DELETE", path: "/v1/packs/250", key: "reader", status: http.StatusForbidden},
+		{method: "DELETE", path: "/v1/catalogs/bolts/packs/250", key: "writer", status: http.StatusNoContent},
+		{method: "DELETE", path: "/v1/packs/250", key: "admin", status: http.StatusNoContent},
+		{method: "GET", path: "/v1/admin/keys/", key: "writer", status: http.StatusForbidden},
+		{method: "GET", path: "/v1/admin/keys/", key: "admin", status: http.StatusOK},
+	}
+	for _, test := range tests {
+		name := test.method + " " + test.path + " as " + test.key
+		req := httptest.NewRequest(test.method, test.path, strings.NewReader(test.body))
+		if test.key != "" {
+			req.Header.Set("X-API-Key", test.key)
+		}
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.status, rr.Code, name)
+		if rr.Code == http.StatusUnauthorized {
+			assert.NotEmpty(t, rr.Header().Get("WWW-Authenticate"), name)
+		}
+	}
+}
+
+func TestCreateKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	created := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	keys := mocks.NewMockKeyStoreInterface(ctrl)
+	keys.EXPECT().Create("erp", []auth.Scope{auth.ScopePacksRead}).Return(auth.APIKey{ID: "k1", Name: "erp", Scopes: []auth.Scope{auth.ScopePacksRead}, CreatedAt: created}, "pko_secret", nil).Times(1)
+
+	handler := New(mocks.NewMockRegistryInterface(ctrl), WithKeyStore(keys))
+
+	req := httptest.NewRequest("POST", "/v1/admin/keys", strings.NewReader(`{"name": "erp", "scopes": ["packs:read"]}`))
+	rr := httptest.NewRecorder()
+	handler.CreateKey(rr, req)
+
+	assert.Equal(t, http.StatusCreated, rr.Code)
+	var resp struct {
+		Data map[string]interface{} `json:"data"`
+	}
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+	assert.Equal(t, "k1", resp.Data["id"])
+	assert.Equal(t, "pko_secret", resp.Data["key"])
+	assert.NotContains(t, resp.Data, "hash")
+
+	for body, field := range map[string]string{
+		`{"name": "", "scopes": ["packs:read"]}`: "name",
+		`{"name": "erp", "scopes": ["root"]}`:    "scopes",
+		`{"name": "erp"}`:                        "scopes",
+	} {
+		req := httptest.NewRequest("POST", "/v1/admin/keys", strings.NewReader(body))
+		rr := httptest.NewRecorder()
+		handler.CreateKey(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, body)
+		var p Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+		assert.Equal(t, field, p.Field, body)
+	}
+}
+
+func TestRevokeKey(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	keys := mocks.NewMockKeyStoreInterface(ctrl)
+	keys.EXPECT().Revoke("k1").Return(nil).Times(1)
+	keys.EXPECT().Revoke("k2").Return(auth.ErrKeyNotFound).Times(1)
+
+	handler := New(mocks.NewMockRegistryInterface(ctrl), WithKeyStore(keys))
+
+	rr := httptest.NewRecorder()
+	handler.RevokeKey(rr, httptest.NewRequest("DELETE", "/v1/admin/keys/k1", nil))
+	assert.Equal(t, http.StatusNoContent, rr.Code)
+
+	rr = httptest.NewRecorder()
+	handler.RevokeKey(rr, httptest.NewRequest("DELETE", "/v1/admin/keys/k2", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	// Without a key store there are no keys to manage.
+	rr = httptest.NewRecorder()
+	New(mocks.NewMockRegistryInterface(ctrl)).RevokeKey(rr, httptest.NewRequest("DELETE", "/v1/admin/keys/k1", nil))
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+}
+
+func TestBearerAuthentication(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{sizer.NewPack(250)}, nil).AnyTimes()
+	mockOptimizer.EXPECT().RemoveSize(250).Return(nil).AnyTimes()
+
+	tokens := mocks.NewMockTokenVerifierInterface(ctrl)
+	tokens.EXPECT().Verify("reader").Return(auth.Principal{Subject: "erp", Scopes: []auth.Scope{auth.ScopePacksRead}}, nil).AnyTimes()
+	tokens.EXPECT().Verify("expired").Return(auth.Principal{}, fmt.Errorf("%w: token has expired", auth.ErrInvalidToken)).AnyTimes()
+	keys := mocks.NewMockKeyStoreInterface(ctrl)
+	keys.EXPECT().Authenticate("writer").Return(auth.APIKey{ID: "w", Scopes: []auth.Scope{auth.ScopePacksWrite}}, nil).AnyTimes()
+
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(mockOptimizer, nil).AnyTimes()
+
+	var subject string
+	h := New(registry, WithKeyStore(keys), WithTokenVerifier(tokens))
+	router, err := NewRouter(h)
+	require.NoError(t, err)
+
+	tests := []struct {
+		method, path, authorization, key string
+		status                           int
+	}{
+		{method: "GET", path: "/v1/packs/", status: http.StatusUnauthorized},
+		{method: "GET", path: "/v1/packs/", authorization: "Bearer reader", status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/", authorization: "bearer reader", status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/", authorization: "Bearer expired", status: http.StatusUnauthorized},
+		{method: "GET", path: "/v1/packs/", authorization: "Basic cmVhZGVy", status: http.StatusUnauthorized},
+		{method: "DELETE", path: "/v1/packs/250", authorization: "Bearer reader", status: http.StatusForbidden},
+		{method: "DELETE", path: "/v1/packs/250", key: "writer", status: http.StatusNoContent},
+	}
+	for _, test := range tests {
+		name := test.method + " " + test.path + " with " + test.authorization + test.key
+		req := httptest.NewRequest(test.method, test.path, nil)
+		if test.authorization != "" {
+			req.Header.Set("Authorization", test.authorization)
+		}
+		if test.key != "" {
+			req.Header.Set("X-API-Key", test.key)
+		}
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.status, rr.Code, name)
+		if rr.Code == http.StatusUnauthorized {
+			assert.Equal(t, []string{"Bearer", `APIKey header="X-API-Key"`}, rr.Header().Values("WWW-Authenticate"), name)
+		}
+	}
+
+	// The subject of the token is attached to the request context.
+	req := httptest.NewRequest("GET", "/v1/packs/", nil)
+	req.Header.Set("Authorization", "Bearer reader")
+	h.Authenticate(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		principal, _ := auth.PrincipalFrom(r.Context())
+		subject = principal.Subject
+	})).ServeHTTP(httptest.NewRecorder(), req)
+	assert.Equal(t, "jwt:erp", subject)
+}
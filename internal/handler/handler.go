@@ -1,29 +1,82 @@
 package handler
 
 import (
+	"bytes"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"net/http"
+	"path"
 	"strconv"
 	"strings"
 
+	"github.com/go-chi/chi/v5"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 )
 
+// maxBatchLines is the largest number of lines accepted by POST /v1/orders/batch.
+const maxBatchLines = 1000
+
+// maxImportBytes is the largest body accepted by POST /v1/packs/import.
+const maxImportBytes = 1 << 20
+
+// contentTypes maps each import and export format to its media type.
+var contentTypes = map[sizer.Format]string{
+	sizer.FormatJSON: "application/json",
+	sizer.FormatCSV:  "text/csv",
+	sizer.FormatYAML: "application/yaml",
+}
+
 // HandlerInterface defines the HTTP handler contract for pack optimizer endpoints.
 type HandlerInterface interface {
 	HealthHandler(w http.ResponseWriter, r *http.Request)
 	CalculateOrder(w http.ResponseWriter, r *http.Request)
+	CalculateBatch(w http.ResponseWriter, r *http.Request)
 	GetPacks(w http.ResponseWriter, r *http.Request)
 	PostPacks(w http.ResponseWriter, r *http.Request)
+	PutPacks(w http.ResponseWriter, r *http.Request)
+	PatchPacks(w http.ResponseWriter, r *http.Request)
+	EnablePacks(w http.ResponseWriter, r *http.Request)
+	DisablePacks(w http.ResponseWriter, r *http.Request)
+	GetVersions(w http.ResponseWriter, r *http.Request)
+	GetVersion(w http.ResponseWriter, r *http.Request)
+	RestoreVersion(w http.ResponseWriter, r *http.Request)
+	ExportPacks(w http.ResponseWriter, r *http.Request)
+	ImportPacks(w http.ResponseWriter, r *http.Request)
 	DeletePacks(w http.ResponseWriter, r *http.Request)
 	NotFoundHandler(w http.ResponseWriter, r *http.Request)
+	ListKeys(w http.ResponseWriter, r *http.Request)
+	CreateKey(w http.ResponseWriter, r *http.Request)
+	RevokeKey(w http.ResponseWriter, r *http.Request)
+	CacheStats(w http.ResponseWriter, r *http.Request)
+	Authenticate(next http.Handler) http.Handler
+	Require(scope auth.Scope) func(http.Handler) http.Handler
+	RateLimit(budget ratelimit.Budget) func(http.Handler) http.Handler
+	Idempotent(next http.Handler) http.Handler
 }
 
-// Handler implements HTTP endpoints for managing and calculating packaging sizes.
+// Handler implements HTTP endpoints for managing and calculating packaging sizes. Routes
+// with an {id} parameter act on that catalog, the others on sizer.DefaultCatalog. Clients
+// authenticate with the API keys of keys or the bearer tokens tokens verifies, when set,
+// and are rate limited by limiter, when set. Repeated requests with an Idempotency-Key
+// header are answered from idempotency, when set.
 type Handler struct {
-	optimizer optimizer.OptimizerInterface
+	catalogs optimizer.RegistryInterface
+	keys     auth.KeyStoreInterface
+	tokens   auth.TokenVerifierInterface
+	limiter  ratelimit.LimiterInterface
+
+	idempotency idempotency.StoreInterface
 }
 
+// Option configures a Handler.
+type Option func(*Handler)
+
 // Response defines a generic response structure for all endpoints.
 type Response struct {
 	Status  string      `json:"status"`
@@ -31,41 +84,62 @@ type Response struct {
 	Data    interface{} `json:"data,omitempty"`
 }
 
-// New creates a new Handler instance with the given optimizer implementation.
-func New(opt optimizer.OptimizerInterface) *Handler {
-	return &Handler{
-		optimizer: opt,
+// New creates a new Handler instance resolving catalogs with the given registry.
+func New(catalogs optimizer.RegistryInterface, options ...Option) *Handler {
+	h := &Handler{
+		catalogs: catalogs,
 	}
+	for _, option := range options {
+		option(h)
+	}
+	return h
 }
 
 // GetPacks handles GET /v1/packs
-// Returns the list of available pack sizes from the optimizer.
+// Returns the list of available pack sizes with their prices and stock from the optimizer.
 func (h *Handler) GetPacks(w http.ResponseWriter, r *http.Request) {
-	response := Response{}
-	sizes, err := h.optimizer.GetAllSizes()
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	packs, err := opt.GetAllPacks()
 	if err != nil {
-		response.Message = "no sizes found"
-		writeJSONResponse(w, http.StatusNotFound, response)
+		writeError(w, r, err, "Failed to read pack sizes")
 		return
 	}
 
-	response.Data = sizes
+	response := Response{
+		Data: packs,
+	}
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
 // PostPacks handles POST /v1/packs
-// Adds a new pack size to the system via the optimizer.
+// Adds a new pack size, with its optional price, stock and metadata, to the system via the
+// optimizer. Packs are enabled unless the body says otherwise.
 func (h *Handler) PostPacks(w http.ResponseWriter, r *http.Request) {
-	var req struct {
-		Size int `json:"size"`
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
 	}
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Size <= 0 {
-		http.Error(w, "Invalid or missing pack size", http.StatusBadRequest)
+
+	req := sizer.NewPack(0)
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, bodyProblem(err))
+		return
+	}
+	if req.Size <= 0 {
+		writeProblem(w, r, invalidField("size", "Invalid or missing pack size"))
+		return
+	}
+	if err := req.Validate(); err != nil {
+		writeError(w, r, err, "Invalid pack")
 		return
 	}
 
-	if err := h.optimizer.AddSize(req.Size); err != nil {
-		http.Error(w, "Failed to add pack size", http.StatusInternalServerError)
+	if err := opt.AddPack(req); err != nil {
+		writeError(w, r, err, "Failed to add pack size")
 		return
 	}
 
@@ -78,19 +152,18 @@ func (h *Handler) PostPacks(w http.ResponseWriter, r *http.Request) {
 // DeletePacks handles DELETE /v1/packs/{size}
 // Removes a specific pack size based on the size provided in the URL path.
 func (h *Handler) DeletePacks(w http.ResponseWriter, r *http.Request) {
-	parts := strings.Split(r.URL.Path, "/")
-	if len(parts) < 4 {
-		http.Error(w, "Invalid path", http.StatusBadRequest)
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
 		return
 	}
-	size, err := strconv.Atoi(parts[3])
-	if err != nil {
-		http.Error(w, "Invalid pack size", http.StatusBadRequest)
+
+	size, ok := sizeFromPath(w, r, r.URL.Path)
+	if !ok {
 		return
 	}
 
-	if err := h.optimizer.RemoveSize(size); err != nil {
-		http.Error(w, err.Error(), http.StatusNotFound)
+	if err := opt.RemoveSize(size); err != nil {
+		writeError(w, r, err, "Failed to remove pack size")
 		return
 	}
 
@@ -98,39 +171,548 @@ func (h *Handler) DeletePacks(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusNoContent, response)
 }
 
+// PutPacks handles PUT /v1/packs
+// Replaces the whole set of pack sizes with the body, a JSON array of pack objects or bare
+// sizes, in one atomic write. Entries are enabled unless they say otherwise.
+func (h *Handler) PutPacks(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	var entries []json.RawMessage
+	if err := json.NewDecoder(r.Body).Decode(&entries); err != nil || len(entries) == 0 {
+		writeProblem(w, r, newProblem(CodeInvalidRequest, "Invalid request body: expected a non-empty array of packs"))
+		return
+	}
+
+	packs := make([]sizer.Pack, len(entries))
+	seen := make(map[int]bool, len(entries))
+	for i, entry := range entries {
+		packs[i] = sizer.NewPack(0)
+		err := json.Unmarshal(entry, &packs[i].Size)
+		if err != nil {
+			err = json.Unmarshal(entry, &packs[i])
+		}
+		if err == nil {
+			err = packs[i].Validate()
+		}
+		if err == nil && seen[packs[i].Size] {
+			err = &sizer.FieldError{Field: "size", Message: "pack size given more than once"}
+		}
+		if err != nil {
+			field := fmt.Sprintf("[%d]", i)
+			var fieldErr *sizer.FieldError
+			if errors.As(err, &fieldErr) {
+				field += "." + fieldErr.Field
+			}
+			writeProblem(w, r, invalidField(field, fmt.Sprintf("pack %d: %v", i, err)))
+			return
+		}
+		seen[packs[i].Size] = true
+	}
+
+	if err := opt.ReplaceSizes(packs); err != nil {
+		writeError(w, r, err, "Failed to replace pack sizes")
+		return
+	}
+
+	response := Response{
+		Data: packs,
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// PatchPacks handles PATCH /v1/packs/{size}
+// Updates the fields of a stored pack present in the body and returns the updated pack.
+// Fields left out keep their value; labels, when given, replace the stored ones.
+func (h *Handler) PatchPacks(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	size, ok := sizeFromPath(w, r, r.URL.Path)
+	if !ok {
+		return
+	}
+
+	var fields map[string]json.RawMessage
+	body, err := io.ReadAll(r.Body)
+	if err == nil {
+		err = json.Unmarshal(body, &fields)
+	}
+	if err == nil && fields == nil {
+		err = errors.New("expected an object")
+	}
+	if err != nil {
+		writeProblem(w, r, bodyProblem(err))
+		return
+	}
+
+	var (
+		updated sizer.Pack
+		bodyErr error
+	)
+	err = opt.UpdatePack(size, func(p *sizer.Pack) error {
+		if _, ok := fields["labels"]; ok {
+			p.Labels = nil
+		}
+		if bodyErr = json.Unmarshal(body, p); bodyErr != nil {
+			return bodyErr
+		}
+		updated = *p
+		return nil
+	})
+	if bodyErr != nil {
+		writeProblem(w, r, bodyProblem(bodyErr))
+		return
+	}
+	if err != nil {
+		writeError(w, r, err, "Failed to update pack size")
+		return
+	}
+
+	response := Response{
+		Data: updated,
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// EnablePacks handles POST /v1/packs/{size}/enable
+// Brings a disabled pack size back into use for orders.
+func (h *Handler) EnablePacks(w http.ResponseWriter, r *http.Request) {
+	h.setEnabled(w, r, true)
+}
+
+// DisablePacks handles POST /v1/packs/{size}/disable
+// Stops using a pack size for orders without deleting it.
+func (h *Handler) DisablePacks(w http.ResponseWriter, r *http.Request) {
+	h.setEnabled(w, r, false)
+}
+
+// setEnabled sets the state of the pack size in the path segment before the action.
+func (h *Handler) setEnabled(w http.ResponseWriter, r *http.Request, enabled bool) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	size, ok := sizeFromPath(w, r, path.Dir(r.URL.Path))
+	if !ok {
+		return
+	}
+
+	if err := opt.SetEnabled(size, enabled); err != nil {
+		writeError(w, r, err, "Failed to update pack size")
+		return
+	}
+
+	response := Response{
+		Message: "size disabled succesfully",
+	}
+	if enabled {
+		response.Message = "size enabled succesfully"
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// ExportPacks handles GET /v1/packs/export
+// Downloads every pack record, disabled ones included, in the format given by the format
+// query parameter: json (the default), csv or yaml.
+func (h *Handler) ExportPacks(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	format, err := sizer.ParseFormat(r.URL.Query().Get("format"))
+	if err != nil {
+		writeProblem(w, r, errorProblem(err, "").at("format"))
+		return
+	}
+
+	packs, err := opt.GetAllPacks()
+	if err != nil {
+		writeError(w, r, err, "Failed to read pack sizes")
+		return
+	}
+
+	var body bytes.Buffer
+	if err := sizer.Export(&body, format, packs); err != nil {
+		writeError(w, r, err, "Failed to export pack sizes")
+		return
+	}
+
+	w.Header().Set("Content-Type", contentTypes[format])
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=packs.%s", format))
+	w.Write(body.Bytes())
+}
+
+// ImportPacks handles POST /v1/packs/import
+// Stores the pack records held by the body, in the format given by the format query
+// parameter or else by the Content-Type. The mode parameter merges them into the current
+// sizes (the default) or replaces the current sizes with them, and dry_run=true only
+// reports what would change. Malformed records are answered with 422 and one error per
+// line, and nothing is stored.
+func (h *Handler) ImportPacks(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	query := r.URL.Query()
+	format, err := importFormat(query.Get("format"), r.Header.Get("Content-Type"))
+	if err != nil {
+		writeProblem(w, r, errorProblem(err, "").at("format"))
+		return
+	}
+	mode := query.Get("mode")
+	if mode == "" {
+		mode = "merge"
+	}
+	if mode != "merge" && mode != "replace" {
+		writeProblem(w, r, invalidField("mode", "mode must be merge or replace"))
+		return
+	}
+	dryRun := false
+	if v := query.Get("dry_run"); v != "" {
+		if dryRun, err = strconv.ParseBool(v); err != nil {
+			writeProblem(w, r, invalidField("dry_run", "dry_run must be true or false"))
+			return
+		}
+	}
+
+	body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxImportBytes))
+	if err != nil {
+		writeProblem(w, r, bodyProblem(err))
+		return
+	}
+
+	packs, err := sizer.ParseImport(body, format)
+	var importErr *sizer.ImportError
+	if errors.As(err, &importErr) {
+		writeError(w, r, err, "")
+		return
+	}
+	if err != nil {
+		writeProblem(w, r, newProblem(CodeInvalidRequest, err.Error()))
+		return
+	}
+
+	current, err := opt.GetAllPacks()
+	if err != nil {
+		writeError(w, r, err, "Failed to read pack sizes")
+		return
+	}
+	plan := sizer.PlanImport(current, packs, mode == "replace")
+
+	if !dryRun {
+		if mode == "replace" {
+			err = opt.ReplaceSizes(packs)
+		} else {
+			err = opt.MergePacks(packs)
+		}
+		if err != nil {
+			writeError(w, r, err, "Failed to import pack sizes")
+			return
+		}
+	}
+
+	response := Response{
+		Data: map[string]interface{}{
+			"mode":      mode,
+			"dry_run":   dryRun,
+			"records":   len(packs),
+			"added":     plan.Added,
+			"updated":   plan.Updated,
+			"removed":   plan.Removed,
+			"unchanged": plan.Unchanged,
+		},
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// importFormat returns the format of an import named by the format query parameter, or
+// else by its media type, defaulting to JSON.
+func importFormat(name, contentType string) (sizer.Format, error) {
+	if name != "" {
+		return sizer.ParseFormat(name)
+	}
+	mediaType := strings.TrimSpace(strings.SplitN(contentType, ";", 2)[0])
+	for format, t := range contentTypes {
+		if strings.EqualFold(mediaType, t) {
+			return format, nil
+		}
+	}
+	if strings.EqualFold(mediaType, "application/x-yaml") || strings.EqualFold(mediaType, "text/yaml") {
+		return sizer.FormatYAML, nil
+	}
+	return sizer.FormatJSON, nil
+}
+
+// GetVersions handles GET /v1/packs/versions
+// Returns every version of the catalog, oldest first, with its timestamp and reason.
+func (h *Handler) GetVersions(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	versions, err := opt.GetVersions()
+	if err != nil {
+		writeError(w, r, err, "Failed to list catalog versions")
+		return
+	}
+	if versions == nil {
+		versions = []sizer.Version{}
+	}
+
+	response := Response{
+		Data: versions,
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// GetVersion handles GET /v1/packs/versions/{v}
+// Returns one version of the catalog with the pack sizes it held.
+func (h *Handler) GetVersion(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	number, ok := numberFromPath(w, r, r.URL.Path, "v", "catalog version")
+	if !ok {
+		return
+	}
+
+	version, err := opt.GetVersion(number)
+	if err != nil {
+		writeError(w, r, err, "Failed to read catalog version")
+		return
+	}
+
+	response := Response{
+		Data: version,
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// RestoreVersion handles POST /v1/packs/versions/{v}/restore
+// Makes the pack sizes of a past version current again, recorded as a new version.
+func (h *Handler) RestoreVersion(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
+	number, ok := numberFromPath(w, r, path.Dir(r.URL.Path), "v", "catalog version")
+	if !ok {
+		return
+	}
+
+	if err := opt.RestoreVersion(number); err != nil {
+		writeError(w, r, err, "Failed to restore catalog version")
+		return
+	}
+
+	response := Response{
+		Message: fmt.Sprintf("version %d restored succesfully", number),
+	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
 // CalculateOrder handles POST /v1/order
 // Calculates the optimal set of packs to fulfill a given quantity.
 func (h *Handler) CalculateOrder(w http.ResponseWriter, r *http.Request) {
+	opt := h.optimizerFor(w, r)
+	if opt == nil {
+		return
+	}
+
 	var req struct {
-		ItemsOrdered int `json:"items_ordered"`
+		ItemsOrdered   int    `json:"items_ordered"`
+		Strategy       string `json:"strategy"`
+		PackWeight     int    `json:"pack_weight"`
+		Mode           string `json:"mode"`
+		MaxOverfill    int    `json:"max_overfill"`
+		Alternatives   int    `json:"alternatives"`
+		CatalogVersion int    `json:"catalog_version"`
 	}
 	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		http.Error(w, "Invalid request body", http.StatusBadRequest)
+		writeProblem(w, r, bodyProblem(err))
 		return
 	}
 
 	if req.ItemsOrdered <= 0 {
-		http.Error(w, "items_ordered must be greater than 0", http.StatusBadRequest)
+		writeProblem(w, r, newProblem(CodeInvalidQuantity, "items_ordered must be greater than 0").at("items_ordered"))
+		return
+	}
+
+	var options []optimizer.CalculateOption
+	if req.Strategy != "" {
+		cost, err := optimizer.ParseCostFunction(req.Strategy, req.PackWeight)
+		if err != nil {
+			field := "strategy"
+			if req.PackWeight < 0 {
+				field = "pack_weight"
+			}
+			writeProblem(w, r, invalidField(field, err.Error()))
+			return
+		}
+		options = append(options, optimizer.UsingCostFunction(cost))
+	}
+	if req.Mode != "" {
+		overfill, err := optimizer.ParseOverfill(req.Mode, req.MaxOverfill)
+		if err != nil {
+			field := "mode"
+			if req.MaxOverfill < 0 {
+				field = "max_overfill"
+			}
+			writeProblem(w, r, invalidField(field, err.Error()))
+			return
+		}
+		options = append(options, optimizer.UsingOverfill(overfill))
+	}
+	if req.Alternatives < 0 || req.Alternatives > optimizer.MaxAlternatives {
+		writeProblem(w, r, invalidField("alternatives", fmt.Sprintf("alternatives must be between 0 and %d", optimizer.MaxAlternatives)))
+		return
+	}
+	if req.Alternatives > 0 {
+		options = append(options, optimizer.UsingAlternatives(req.Alternatives))
+	}
+	if req.CatalogVersion < 0 {
+		writeProblem(w, r, invalidField("catalog_version", "catalog_version must not be negative"))
+		return
+	}
+	if req.CatalogVersion > 0 {
+		options = append(options, optimizer.UsingVersion(req.CatalogVersion))
+	}
+
+	result, err := opt.Calculate(req.ItemsOrdered, options...)
+	if err != nil {
+		writeError(w, r, err, "Failed to calculate the order")
+		return
+	}
+
+	resp := orderResponse(result)
+	if result.Alternatives != nil {
+		alternatives := make([]map[string]interface{}, len(result.Alternatives))
+		for i := range result.Alternatives {
+			alternatives[i] = orderResponse(&result.Alternatives[i])
+		}
+		resp["alternatives"] = alternatives
+	}
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(resp)
+}
+
+// CalculateBatch handles POST /v1/orders/batch
+// Calculates every line of a multi-SKU order in parallel. Lines that fail report their
+// own error and status without failing the batch. Each line counts as one request
+// against the calculate rate limit budget.
+func (h *Handler) CalculateBatch(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Lines []optimizer.OrderLine `json:"lines"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		writeProblem(w, r, bodyProblem(err))
+		return
+	}
+	if len(req.Lines) == 0 || len(req.Lines) > maxBatchLines {
+		writeProblem(w, r, invalidField("lines", fmt.Sprintf("lines must hold between 1 and %d entries", maxBatchLines)))
+		return
+	}
+	// Each line is a calculation of its own. The RateLimit middleware took the first from
+	// the budget of the client, the others are taken here.
+	if len(req.Lines) > 1 && !h.allow(w, r, ratelimit.BudgetCalculate, len(req.Lines)-1) {
 		return
 	}
 
-	result := h.optimizer.Calculate(req.ItemsOrdered)
+	batch := optimizer.CalculateBatch(req.Lines, h.resolve, optimizer.DefaultBatchWorkers)
+
+	lines := make([]map[string]interface{}, len(batch.Lines))
+	for i, line := range batch.Lines {
+		lines[i] = map[string]interface{}{
+			"sku":           line.SKU,
+			"items_ordered": line.ItemsOrdered,
+		}
+		if line.Err != nil {
+			p := errorProblem(line.Err, "Failed to calculate the line")
+			lines[i]["status"] = p.Status
+			lines[i]["code"] = p.Code
+			lines[i]["error"] = p.Detail
+			continue
+		}
+		lines[i]["status"] = http.StatusOK
+		lines[i]["result"] = orderResponse(line.Result)
+	}
 
 	resp := map[string]interface{}{
-		"packs":       result.PacksUsed,
-		"total_items": result.TotalItems,
-		"total_packs": result.TotalPacks,
+		"lines":           lines,
+		"items_requested": batch.ItemsRequested,
+		"total_items":     batch.TotalItems,
+		"total_packs":     batch.TotalPacks,
+		"total_cost":      batch.TotalCost,
+		"failed_lines":    batch.Failed,
 	}
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(resp)
 }
 
+// resolve returns the optimizer serving sku, whose pack sizes live in the catalog of the
+// same ID. Lines without a SKU use the default catalog.
+func (h *Handler) resolve(sku string) (optimizer.OptimizerInterface, error) {
+	if sku == "" {
+		sku = sizer.DefaultCatalog
+	}
+	return h.catalogs.Get(sku)
+}
+
+// optimizerFor returns the optimizer of the catalog named by the {id} route parameter, or
+// of the default catalog on routes without one. It answers the request itself and
+// returns nil when the catalog cannot be resolved.
+func (h *Handler) optimizerFor(w http.ResponseWriter, r *http.Request) optimizer.OptimizerInterface {
+	catalog := chi.URLParam(r, "id")
+	if catalog == "" {
+		catalog = sizer.DefaultCatalog
+	}
+
+	opt, err := h.catalogs.Get(catalog)
+	if err != nil {
+		writeError(w, r, err, "Failed to load the catalog")
+		return nil
+	}
+	return opt
+}
+
+// sizeFromPath returns the pack size in the last segment of urlPath. It answers r
+// itself and returns false when the path holds no valid size.
+func sizeFromPath(w http.ResponseWriter, r *http.Request, urlPath string) (int, bool) {
+	return numberFromPath(w, r, urlPath, "size", "pack size")
+}
+
+// numberFromPath returns the number in the last segment of urlPath, the route parameter
+// param, naming it name in the problem it answers with when the path holds no valid
+// number.
+func numberFromPath(w http.ResponseWriter, r *http.Request, urlPath, param, name string) (int, bool) {
+	parts := strings.Split(urlPath, "/")
+	if len(parts) < 4 {
+		writeProblem(w, r, invalidField(param, "Invalid path"))
+		return 0, false
+	}
+	n, err := strconv.Atoi(parts[len(parts)-1])
+	if err != nil {
+		writeProblem(w, r, invalidField(param, "Invalid "+name))
+		return 0, false
+	}
+	return n, true
+}
+
 // NotFoundHandler handles requests to undefined routes.
 func (h *Handler) NotFoundHandler(w http.ResponseWriter, r *http.Request) {
-	response := Response{
-		Message: "route not found",
-	}
-	writeJSONResponse(w, http.StatusNotFound, response)
+	writeProblem(w, r, newProblem(CodeNotFound, "route not found"))
 }
 
 // HealthHandler handles GET /health
@@ -140,12 +722,41 @@ func (h *Handler) HealthHandler(w http.ResponseWriter, r *http.Request) {
 	writeJSONResponse(w, http.StatusOK, response)
 }
 
-// writeJSONResponse encodes and writes a JSON response with the given status code.
-func writeJSONResponse(w http.ResponseWriter, statusCode int, response Response) {
-	response.Status = "error"
-	if statusCode < 400 {
-		response.Status = "success"
+// CacheStats handles GET /v1/admin/cache
+// Returns the hit, miss and eviction counters of the result cache of every loaded
+// catalog, by catalog ID, so that the cache can be sized from real traffic.
+func (h *Handler) CacheStats(w http.ResponseWriter, r *http.Request) {
+	response := Response{
+		Data: h.catalogs.Stats(),
 	}
+	writeJSONResponse(w, http.StatusOK, response)
+}
+
+// orderResponse renders a calculated result in the shape returned by POST /v1/order.
+func orderResponse(result *optimizer.OptimizationResult) map[string]interface{} {
+	resp := map[string]interface{}{
+		"packs":            result.PacksUsed,
+		"breakdown":        result.Breakdown,
+		"items_requested":  result.ItemsRequested,
+		"total_items":      result.TotalItems,
+		"total_packs":      result.TotalPacks,
+		"total_cost":       result.TotalCost,
+		"overfill_items":   result.OverfillItems,
+		"overfill_percent": result.OverfillPercent,
+	}
+	if result.PackCosts != nil {
+		resp["pack_costs"] = result.PackCosts
+	}
+	if result.CatalogVersion > 0 {
+		resp["catalog_version"] = result.CatalogVersion
+	}
+	return resp
+}
+
+// writeJSONResponse encodes and writes a successful JSON response with the given status
+// code. Errors are answered with writeProblem instead.
+func writeJSONResponse(w http.ResponseWriter, statusCode int, response Response) {
+	response.Status = "success"
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(statusCode)
 	json.NewEncoder(w).Encode(response)
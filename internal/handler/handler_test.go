@@ -3,23 +3,34 @@ package handler
 import (
 	"bytes"
 	"encoding/json"
+	"errors"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
 )
 
+// newTestHandler returns a Handler resolving every catalog to opt.
+func newTestHandler(ctrl *gomock.Controller, opt optimizer.OptimizerInterface) *Handler {
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(opt, nil).AnyTimes()
+	return New(registry)
+}
+
 func TestHealthHandler(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
 	req := httptest.NewRequest("GET", "/health", nil)
 	rr := httptest.NewRecorder()
@@ -30,19 +41,41 @@ func TestHealthHandler(t *testing.T) {
 	assert.Equal(t, http.StatusOK, resp.StatusCode)
 }
 
+func TestCacheStats(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Stats().Return(map[string]optimizer.Stats{
+		"default": {Hits: 3, Misses: 1, Evictions: 0, Entries: 1, Capacity: 1024},
+	}).Times(1)
+
+	router, err := NewRouter(New(registry))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/admin/cache", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.JSONEq(t, `{"status": "success", "data": {"default": {"hits": 3, "misses": 1, "evictions": 0, "entries": 1, "capacity": 1024}}}`, rr.Body.String())
+}
+
 func TestCalculateOrder(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
 	mockOptimizer.EXPECT().Calculate(501).Return(&optimizer.OptimizationResult{
-		PacksUsed:  []int{500, 250},
-		TotalItems: 750,
-		TotalPacks: 2,
-	}).Times(1)
+		PacksUsed:       []int{500, 250},
+		Breakdown:       []optimizer.PackCount{{Size: 500, Count: 1}, {Size: 250, Count: 1}},
+		ItemsRequested:  501,
+		TotalItems:      750,
+		TotalPacks:      2,
+		OverfillItems:   249,
+		OverfillPercent: 49.7,
+	}, nil).Times(1)
 
 	body := map[string]int{"items_ordered": 501}
 	jsonBody, _ := json.Marshal(body)
@@ -62,6 +95,10 @@ func TestCalculateOrder(t *testing.T) {
 
 	assert.Equal(t, 750, result.TotalItems)
 	assert.Equal(t, 2, result.TotalPacks)
+	assert.Equal(t, []optimizer.PackCount{{Size: 500, Count: 1}, {Size: 250, Count: 1}}, result.Breakdown)
+	assert.Equal(t, 501, result.ItemsRequested)
+	assert.Equal(t, 249, result.OverfillItems)
+	assert.Equal(t, 49.7, result.OverfillPercent)
 
 	req = httptest.NewRequest("POST", "/v1/order", nil)
 	rr = httptest.NewRecorder()
@@ -72,15 +109,297 @@ func TestCalculateOrder(t *testing.T) {
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
 }
 
+func TestCalculateOrder_Strategy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().Calculate(251, gomock.Any()).Return(&optimizer.OptimizationResult{
+		PacksUsed:  []int{1000},
+		TotalItems: 1000,
+		TotalPacks: 1,
+	}, nil).Times(1)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"items_ordered": 251, "strategy": "fewest_packs"})
+
+	req := httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{"items_ordered": 251, "strategy": "cheapest"})
+
+	req = httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr = httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCalculateOrder_InsufficientStock(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().Calculate(5000).Return(nil, optimizer.ErrInsufficientStock).Times(1)
+
+	jsonBody, _ := json.Marshal(map[string]int{"items_ordered": 5000})
+
+	req := httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusConflict, resp.StatusCode)
+}
+
+func TestCalculateOrder_Mode(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().Calculate(501, gomock.Any()).Return(nil, optimizer.ErrInfeasible).Times(1)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{"items_ordered": 501, "mode": "exact"})
+
+	req := httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusUnprocessableEntity, resp.StatusCode)
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{"items_ordered": 501, "mode": "max_overfill_percent", "max_overfill": -5})
+
+	req = httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr = httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCalculateOrder_Alternatives(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	best := optimizer.OptimizationResult{PacksUsed: []int{1000}, TotalItems: 1000, TotalPacks: 1}
+	other := optimizer.OptimizationResult{PacksUsed: []int{500, 500}, TotalItems: 1000, TotalPacks: 2}
+	result := best
+	result.Alternatives = []optimizer.OptimizationResult{best, other}
+	mockOptimizer.EXPECT().Calculate(1000, gomock.Any()).Return(&result, nil).Times(1)
+
+	jsonBody, _ := json.Marshal(map[string]int{"items_ordered": 1000, "alternatives": 2})
+
+	req := httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Alternatives []struct {
+			Packs []int `json:"packs"`
+		} `json:"alternatives"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if assert.Len(t, body.Alternatives, 2) {
+		assert.Equal(t, []int{500, 500}, body.Alternatives[1].Packs)
+	}
+
+	jsonBody, _ = json.Marshal(map[string]int{"items_ordered": 1000, "alternatives": optimizer.MaxAlternatives + 1})
+
+	req = httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+	rr = httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestCalculateOrder_Errors(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+	}{
+		{err: optimizer.ErrNoSizes, status: http.StatusConflict},
+		{err: optimizer.ErrInsufficientStock, status: http.StatusConflict},
+		{err: optimizer.ErrInfeasible, status: http.StatusUnprocessableEntity},
+		{err: optimizer.ErrAlternativesTooLarge, status: http.StatusUnprocessableEntity},
+		{err: optimizer.ErrInvalidQuantity, status: http.StatusBadRequest},
+		{err: optimizer.ErrQuantityTooLarge, status: http.StatusBadRequest},
+		{err: errors.New("boom"), status: http.StatusInternalServerError},
+	}
+
+	for _, test := range tests {
+		t.Run(test.err.Error(), func(t *testing.T) {
+			ctrl := gomock.NewController(t)
+			defer ctrl.Finish()
+
+			mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+			handler := newTestHandler(ctrl, mockOptimizer)
+
+			mockOptimizer.EXPECT().Calculate(501).Return(nil, test.err).Times(1)
+
+			jsonBody, _ := json.Marshal(map[string]int{"items_ordered": 501})
+
+			req := httptest.NewRequest("POST", "/v1/order", bytes.NewBuffer(jsonBody))
+			rr := httptest.NewRecorder()
+
+			handler.CalculateOrder(rr, req)
+
+			resp := rr.Result()
+			assert.Equal(t, test.status, resp.StatusCode)
+		})
+	}
+}
+
+func TestCalculateOrder_CatalogVersion(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().Calculate(500, gomock.Any()).Return(&optimizer.OptimizationResult{
+		PacksUsed:      []int{500},
+		CatalogVersion: 2,
+	}, nil).Times(1)
+	mockOptimizer.EXPECT().Calculate(500, gomock.Any()).Return(nil, sizer.ErrVersionNotFound).Times(1)
+
+	req := httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 500, "catalog_version": 2}`))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var result map[string]interface{}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&result))
+	assert.Equal(t, float64(2), result["catalog_version"])
+
+	req = httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 500, "catalog_version": 9}`))
+	rr = httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	assert.Equal(t, http.StatusNotFound, rr.Code)
+
+	req = httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 500, "catalog_version": -1}`))
+	rr = httptest.NewRecorder()
+
+	handler.CalculateOrder(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestCalculateBatch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().Calculate(501).Return(&optimizer.OptimizationResult{
+		PacksUsed:      []int{500, 250},
+		ItemsRequested: 501,
+		TotalItems:     750,
+		TotalPacks:     2,
+	}, nil).Times(1)
+	mockOptimizer.EXPECT().Calculate(0).Return(nil, optimizer.ErrInvalidQuantity).Times(1)
+
+	jsonBody, _ := json.Marshal(map[string]interface{}{
+		"lines": []map[string]interface{}{
+			{"sku": "bolts", "items_ordered": 501},
+			{"sku": "nuts", "items_ordered": 0},
+		},
+	})
+
+	req := httptest.NewRequest("POST", "/v1/orders/batch", bytes.NewBuffer(jsonBody))
+	rr := httptest.NewRecorder()
+
+	handler.CalculateBatch(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Lines []struct {
+			SKU    string `json:"sku"`
+			Status int    `json:"status"`
+			Error  string `json:"error"`
+			Result struct {
+				Packs []int `json:"packs"`
+			} `json:"result"`
+		} `json:"lines"`
+		TotalItems  int `json:"total_items"`
+		FailedLines int `json:"failed_lines"`
+	}
+	json.NewDecoder(resp.Body).Decode(&body)
+	if assert.Len(t, body.Lines, 2) {
+		assert.Equal(t, "bolts", body.Lines[0].SKU)
+		assert.Equal(t, http.StatusOK, body.Lines[0].Status)
+		assert.Equal(t, []int{500, 250}, body.Lines[0].Result.Packs)
+		assert.Equal(t, http.StatusBadRequest, body.Lines[1].Status)
+		assert.Equal(t, optimizer.ErrInvalidQuantity.Error(), body.Lines[1].Error)
+	}
+	assert.Equal(t, 750, body.TotalItems)
+	assert.Equal(t, 1, body.FailedLines)
+}
+
+func TestCalculateBatch_InvalidBody(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	handler := newTestHandler(ctrl, mocks.NewMockOptimizerInterface(ctrl))
+
+	for _, body := range []string{"{", `{"lines": []}`} {
+		req := httptest.NewRequest("POST", "/v1/orders/batch", bytes.NewBufferString(body))
+		rr := httptest.NewRecorder()
+
+		handler.CalculateBatch(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Result().StatusCode)
+	}
+}
+
 func TestGetPacks(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
-	mockOptimizer.EXPECT().GetAllSizes().Return([]int{250, 500, 1000}, nil).Times(1)
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{{Size: 250, Price: 1.5}, {Size: 500}, {Size: 1000}}, nil).Times(1)
 
 	req := httptest.NewRequest("GET", "/v1/packs", nil)
 	rr := httptest.NewRecorder()
@@ -95,9 +414,11 @@ func TestGetPacks(t *testing.T) {
 		t.Fatalf("Failed to decode response: %v", err)
 	}
 
-	assert.Equal(t, float64(250), payload.Data.([]interface{})[0])
-	assert.Equal(t, float64(500), payload.Data.([]interface{})[1])
-	assert.Equal(t, float64(1000), payload.Data.([]interface{})[2])
+	packs := payload.Data.([]interface{})
+	assert.Equal(t, float64(250), packs[0].(map[string]interface{})["size"])
+	assert.Equal(t, 1.5, packs[0].(map[string]interface{})["price"])
+	assert.Equal(t, float64(500), packs[1].(map[string]interface{})["size"])
+	assert.Equal(t, float64(1000), packs[2].(map[string]interface{})["size"])
 }
 
 func TestPostPacks(t *testing.T) {
@@ -106,11 +427,11 @@ func TestPostPacks(t *testing.T) {
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
-	mockOptimizer.EXPECT().AddSize(1500).Return(nil).Times(1)
+	mockOptimizer.EXPECT().AddPack(sizer.Pack{Size: 1500, Enabled: true, Price: 9.99}).Return(nil).Times(1)
 
-	body := map[string]int{"size": 1500}
+	body := map[string]interface{}{"size": 1500, "price": 9.99}
 	jsonBody, _ := json.Marshal(body)
 
 	req := httptest.NewRequest("POST", "/v1/packs", bytes.NewBuffer(jsonBody))
@@ -128,6 +449,291 @@ func TestPostPacks(t *testing.T) {
 
 	resp = rr.Result()
 	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{"size": 1500, "stock": -1})
+	req = httptest.NewRequest("POST", "/v1/packs", bytes.NewBuffer(jsonBody))
+	rr = httptest.NewRecorder()
+
+	handler.PostPacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	jsonBody, _ = json.Marshal(map[string]interface{}{"size": 1500, "price": -1})
+	req = httptest.NewRequest("POST", "/v1/packs", bytes.NewBuffer(jsonBody))
+	rr = httptest.NewRecorder()
+
+	handler.PostPacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestPutPacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().ReplaceSizes([]sizer.Pack{
+		sizer.NewPack(300),
+		{Size: 600, Enabled: true, Price: 4},
+		{Size: 1200},
+	}).Return(nil).Times(1)
+
+	req := httptest.NewRequest("PUT", "/v1/packs", strings.NewReader(`[300, {"size": 600, "price": 4}, {"size": 1200, "enabled": false}]`))
+	rr := httptest.NewRecorder()
+
+	handler.PutPacks(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	for _, body := range []string{`[]`, `{"size": 300}`, `[300, 300]`, `[0]`, `[{"size": 300, "price": -1}]`} {
+		req = httptest.NewRequest("PUT", "/v1/packs", strings.NewReader(body))
+		rr = httptest.NewRecorder()
+
+		handler.PutPacks(rr, req)
+
+		resp = rr.Result()
+		assert.Equal(t, http.StatusBadRequest, resp.StatusCode, body)
+	}
+}
+
+func TestPatchPacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	stored := sizer.Pack{Size: 500, Name: "Box", Enabled: true, Price: 2, Labels: map[string]string{"a": "1"}}
+	mockOptimizer.EXPECT().UpdatePack(500, gomock.Any()).DoAndReturn(func(size int, update func(*sizer.Pack) error) error {
+		p := stored
+		if err := update(&p); err != nil {
+			return err
+		}
+		return p.Validate()
+	}).Times(2)
+	mockOptimizer.EXPECT().UpdatePack(600, gomock.Any()).Return(sizer.ErrSizeNotFound).Times(1)
+
+	req := httptest.NewRequest("PATCH", "/v1/packs/500", strings.NewReader(`{"enabled":false,"labels":{"b":"2"}}`))
+	rr := httptest.NewRecorder()
+
+	handler.PatchPacks(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	var body struct {
+		Data sizer.Pack `json:"data"`
+	}
+	assert.NoError(t, json.NewDecoder(resp.Body).Decode(&body))
+	assert.Equal(t, sizer.Pack{Size: 500, Name: "Box", Price: 2, Labels: map[string]string{"b": "2"}}, body.Data)
+
+	req = httptest.NewRequest("PATCH", "/v1/packs/500", strings.NewReader(`{"weight":-1}`))
+	rr = httptest.NewRecorder()
+
+	handler.PatchPacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	req = httptest.NewRequest("PATCH", "/v1/packs/600", strings.NewReader(`{"name":"Crate"}`))
+	rr = httptest.NewRecorder()
+
+	handler.PatchPacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("PATCH", "/v1/packs/500", strings.NewReader(`[]`))
+	rr = httptest.NewRecorder()
+
+	handler.PatchPacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestEnableDisablePacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().SetEnabled(500, false).Return(nil).Times(1)
+	mockOptimizer.EXPECT().SetEnabled(500, true).Return(nil).Times(1)
+	mockOptimizer.EXPECT().SetEnabled(600, true).Return(sizer.ErrSizeNotFound).Times(1)
+
+	req := httptest.NewRequest("POST", "/v1/packs/500/disable", nil)
+	rr := httptest.NewRecorder()
+
+	handler.DisablePacks(rr, req)
+
+	resp := rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("POST", "/v1/packs/500/enable", nil)
+	rr = httptest.NewRecorder()
+
+	handler.EnablePacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	req = httptest.NewRequest("POST", "/v1/packs/600/enable", nil)
+	rr = httptest.NewRecorder()
+
+	handler.EnablePacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	req = httptest.NewRequest("POST", "/v1/packs/a/enable", nil)
+	rr = httptest.NewRecorder()
+
+	handler.EnablePacks(rr, req)
+
+	resp = rr.Result()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+}
+
+func TestExportPacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{{Size: 500, Enabled: true, Price: 2}}, nil).Times(2)
+
+	req := httptest.NewRequest("GET", "/v1/packs/export?format=csv", nil)
+	rr := httptest.NewRecorder()
+
+	handler.ExportPacks(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "text/csv", rr.Header().Get("Content-Type"))
+	assert.Equal(t, "size,code,name,enabled,price,stock,weight,length,width,height,labels\n500,,,true,2,,,,,,\n", rr.Body.String())
+
+	req = httptest.NewRequest("GET", "/v1/packs/export", nil)
+	rr = httptest.NewRecorder()
+
+	handler.ExportPacks(rr, req)
+
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "application/json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `[{"size": 500, "enabled": true, "price": 2}]`, rr.Body.String())
+
+	req = httptest.NewRequest("GET", "/v1/packs/export?format=xml", nil)
+	rr = httptest.NewRecorder()
+
+	handler.ExportPacks(rr, req)
+
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestImportPacks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	imported := []sizer.Pack{sizer.NewPack(600), {Size: 250, Enabled: true, Price: 1}}
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{sizer.NewPack(500), sizer.NewPack(250)}, nil).Times(3)
+	mockOptimizer.EXPECT().MergePacks(imported).Return(nil).Times(1)
+	mockOptimizer.EXPECT().ReplaceSizes(imported).Return(nil).Times(1)
+
+	body := "size,price\n600,\n250,1\n"
+	tests := []struct {
+		query   string
+		removed []interface{}
+	}{
+		{query: "?format=csv&dry_run=true", removed: []interface{}{}},
+		{query: "?format=csv", removed: []interface{}{}},
+		{query: "?format=csv&mode=replace", removed: []interface{}{float64(500)}},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest("POST", "/v1/packs/import"+test.query, strings.NewReader(body))
+		rr := httptest.NewRecorder()
+
+		handler.ImportPacks(rr, req)
+
+		require.Equal(t, http.StatusOK, rr.Code, test.query)
+
+		var resp struct {
+			Data map[string]interface{} `json:"data"`
+		}
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&resp))
+		assert.Equal(t, []interface{}{float64(600)}, resp.Data["added"], test.query)
+		assert.Equal(t, []interface{}{float64(250)}, resp.Data["updated"], test.query)
+		assert.Equal(t, test.removed, resp.Data["removed"], test.query)
+	}
+
+	req := httptest.NewRequest("POST", "/v1/packs/import", strings.NewReader("- 600\n- size: -1\n"))
+	req.Header.Set("Content-Type", "application/yaml")
+	rr := httptest.NewRecorder()
+
+	handler.ImportPacks(rr, req)
+
+	assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+	assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"))
+	assert.JSONEq(t, `{"type": "/problems/invalid-records", "title": "Invalid records", "status": 422, "detail": "invalid records, nothing was imported", "instance": "/v1/packs/import", "code": "invalid-records", "errors": [{"line": 2, "message": "pack size must be greater than 0"}]}`, rr.Body.String())
+
+	for _, query := range []string{"?mode=upsert", "?dry_run=maybe", "?format=xml"} {
+		req = httptest.NewRequest("POST", "/v1/packs/import"+query, strings.NewReader("[600]"))
+		rr = httptest.NewRecorder()
+
+		handler.ImportPacks(rr, req)
+
+		assert.Equal(t, http.StatusBadRequest, rr.Code, query)
+	}
+}
+
+func TestVersions(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+
+	handler := newTestHandler(ctrl, mockOptimizer)
+
+	mockOptimizer.EXPECT().GetVersions().Return([]sizer.Version{{Number: 1, Reason: "seed catalog"}}, nil).Times(1)
+	mockOptimizer.EXPECT().GetVersion(1).Return(sizer.Version{Number: 1, Packs: []sizer.Pack{sizer.NewPack(250)}}, nil).Times(1)
+	mockOptimizer.EXPECT().GetVersion(2).Return(sizer.Version{}, sizer.ErrVersionNotFound).Times(1)
+	mockOptimizer.EXPECT().RestoreVersion(1).Return(nil).Times(1)
+	mockOptimizer.EXPECT().RestoreVersion(2).Return(sizer.ErrVersionNotFound).Times(1)
+
+	tests := []struct {
+		method  string
+		path    string
+		handler http.HandlerFunc
+		status  int
+	}{
+		{method: "GET", path: "/v1/packs/versions", handler: handler.GetVersions, status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/versions/1", handler: handler.GetVersion, status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/versions/2", handler: handler.GetVersion, status: http.StatusNotFound},
+		{method: "GET", path: "/v1/packs/versions/a", handler: handler.GetVersion, status: http.StatusBadRequest},
+		{method: "POST", path: "/v1/packs/versions/1/restore", handler: handler.RestoreVersion, status: http.StatusOK},
+		{method: "POST", path: "/v1/packs/versions/2/restore", handler: handler.RestoreVersion, status: http.StatusNotFound},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, nil)
+		rr := httptest.NewRecorder()
+
+		test.handler(rr, req)
+
+		assert.Equal(t, test.status, rr.Code, test.path)
+	}
 }
 
 func TestDeletePacks(t *testing.T) {
@@ -136,7 +742,7 @@ func TestDeletePacks(t *testing.T) {
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
 	mockOptimizer.EXPECT().RemoveSize(500).Return(nil).Times(1)
 
@@ -171,7 +777,7 @@ func TestNotFoundHandler(t *testing.T) {
 
 	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
 
-	handler := New(mockOptimizer)
+	handler := newTestHandler(ctrl, mockOptimizer)
 
 	req := httptest.NewRequest("GET", "/nonexistent", nil)
 	rr := httptest.NewRecorder()
@@ -189,3 +795,46 @@ func TestNewRouter(t *testing.T) {
 	_, err = NewRouter(&Handler{})
 	assert.NoError(t, err)
 }
+
+func TestCatalogRoutes(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	defaultOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	boltsOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(sizer.DefaultCatalog).Return(defaultOptimizer, nil).AnyTimes()
+	registry.EXPECT().Get("bolts").Return(boltsOptimizer, nil).AnyTimes()
+	registry.EXPECT().Get("bad.id").Return(nil, sizer.ErrInvalidCatalog).AnyTimes()
+
+	router, err := NewRouter(New(registry))
+	require.NoError(t, err)
+
+	boltsOptimizer.EXPECT().Calculate(50).Return(&optimizer.OptimizationResult{PacksUsed: []int{40, 20}}, nil).Times(1)
+	boltsOptimizer.EXPECT().RemoveSize(40).Return(nil).Times(1)
+	boltsOptimizer.EXPECT().SetEnabled(20, false).Return(nil).Times(1)
+	boltsOptimizer.EXPECT().GetVersions().Return(nil, nil).Times(1)
+	defaultOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{{Size: 250}}, nil).Times(1)
+
+	tests := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{method: "POST", path: "/v1/catalogs/bolts/order", body: `{"items_ordered": 50}`, status: http.StatusOK},
+		{method: "DELETE", path: "/v1/catalogs/bolts/packs/40", status: http.StatusNoContent},
+		{method: "POST", path: "/v1/catalogs/bolts/packs/20/disable", status: http.StatusOK},
+		{method: "GET", path: "/v1/catalogs/bolts/packs/versions", status: http.StatusOK},
+		{method: "GET", path: "/v1/packs/", status: http.StatusOK},
+		{method: "GET", path: "/v1/catalogs/bad.id/packs/", status: http.StatusBadRequest},
+	}
+	for _, test := range tests {
+		req := httptest.NewRequest(test.method, test.path, bytes.NewBufferString(test.body))
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.status, rr.Code, test.path)
+	}
+}
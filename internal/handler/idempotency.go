@@ -0,0 +1,121 @@
+package handler
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
+)
+
+// idempotencyKeyHeader is the request header carrying the idempotency key of a request.
+const idempotencyKeyHeader = "Idempotency-Key"
+
+// idempotentReplayedHeader is set on responses replayed for a repeated idempotency key.
+const idempotentReplayedHeader = "Idempotent-Replayed"
+
+// maxIdempotencyKey is the longest idempotency key accepted.
+const maxIdempotencyKey = 255
+
+// maxIdempotentBytes is the largest body accepted with an idempotency key, which must be
+// read before the request is served to tell repeats apart.
+const maxIdempotentBytes = 1 << 20
+
+// WithIdempotencyStore honours the Idempotency-Key header with the responses kept in
+// store. Without it the header is ignored.
+func WithIdempotencyStore(store idempotency.StoreInterface) Option {
+	return func(h *Handler) {
+		h.idempotency = store
+	}
+}
+
+// Idempotent is the middleware replaying the first response to a request with an
+// Idempotency-Key header for repeats of it, instead of serving them again. A key is
+// scoped to its client, as told apart by RateLimit, and reusing it for a request with
+// another method, path or body is answered with 422. Server errors are not stored, so
+// that the request can be retried.
+func (h *Handler) Idempotent(next http.Handler) http.Handler {
+	if h.idempotency == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.Header.Get(idempotencyKeyHeader)
+		if key == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if len(key) > maxIdempotencyKey {
+			writeProblem(w, r, newProblem(CodeInvalidRequest, fmt.Sprintf("%s must not be longer than %d characters", idempotencyKeyHeader, maxIdempotencyKey)))
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxIdempotentBytes))
+		if err != nil {
+			writeProblem(w, r, bodyProblem(err))
+			return
+		}
+		r.Body = io.NopCloser(bytes.NewReader(body))
+
+		key = clientOf(r) + " " + key
+		fingerprint := idempotency.Fingerprint([]byte(r.Method), []byte(r.URL.RequestURI()), body)
+		stored, err := h.idempotency.Begin(key, fingerprint)
+		if err != nil {
+			writeError(w, r, err, "Failed to check the idempotency key")
+			return
+		}
+		if stored != nil {
+			replay(w, *stored)
+			return
+		}
+
+		rec := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		completed := false
+		defer func() {
+			if !completed {
+				h.idempotency.Abandon(key)
+			}
+		}()
+		next.ServeHTTP(rec, r)
+
+		if rec.status < http.StatusInternalServerError {
+			// The response is already written, so a failure to store it only means that a
+			// repeat is served again.
+			completed = h.idempotency.Complete(key, fingerprint, idempotency.Response{
+				Status:      rec.status,
+				ContentType: rec.Header().Get("Content-Type"),
+				Body:        rec.body.Bytes(),
+			}) == nil
+		}
+	})
+}
+
+// replay writes res, a response stored for an idempotency key.
+func replay(w http.ResponseWriter, res idempotency.Response) {
+	if res.ContentType != "" {
+		w.Header().Set("Content-Type", res.ContentType)
+	}
+	w.Header().Set(idempotentReplayedHeader, "true")
+	w.WriteHeader(res.Status)
+	w.Write(res.Body)
+}
+
+// responseRecorder is an http.ResponseWriter keeping a copy of the status and body it
+// writes.
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+// WriteHeader records status and writes it.
+func (rec *responseRecorder) WriteHeader(status int) {
+	rec.status = status
+	rec.ResponseWriter.WriteHeader(status)
+}
+
+// Write records b and writes it.
+func (rec *responseRecorder) Write(b []byte) (int, error) {
+	rec.body.Write(b)
+	return rec.ResponseWriter.Write(b)
+}
@@ -0,0 +1,100 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func TestIdempotent(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	require.NoError(t, err)
+	defer db.Close()
+	store := idempotency.NewStore(db, time.Hour)
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().AddPack(gomock.Any()).Return(nil).Times(1)
+	mockOptimizer.EXPECT().Calculate(250).Return(&optimizer.OptimizationResult{PacksUsed: []int{250}}, nil).Times(1)
+	gomock.InOrder(
+		mockOptimizer.EXPECT().RemoveSize(500).Return(errors.New("disk on fire")),
+		mockOptimizer.EXPECT().RemoveSize(500).Return(nil),
+	)
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(mockOptimizer, nil).AnyTimes()
+
+	router, err := NewRouter(New(registry, WithIdempotencyStore(store)))
+	require.NoError(t, err)
+
+	send := func(method, target, body, key string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, target, strings.NewReader(body))
+		if key != "" {
+			req.Header.Set("Idempotency-Key", key)
+		}
+		rr := httptest.NewRecorder()
+		router.ServeHTTP(rr, req)
+		return rr
+	}
+
+	// A retried request is served once, and its response replayed.
+	first := send("POST", "/v1/packs/", `{"size": 250}`, "add-250")
+	assert.Equal(t, http.StatusCreated, first.Code)
+	assert.Empty(t, first.Header().Get("Idempotent-Replayed"))
+	repeat := send("POST", "/v1/packs/", `{"size": 250}`, "add-250")
+	assert.Equal(t, http.StatusCreated, repeat.Code)
+	assert.Equal(t, "true", repeat.Header().Get("Idempotent-Replayed"))
+	assert.Equal(t, first.Header().Get("Content-Type"), repeat.Header().Get("Content-Type"))
+	assert.Equal(t, first.Body.String(), repeat.Body.String())
+
+	// Reusing a key for another request is refused.
+	for _, rr := range []*httptest.ResponseRecorder{
+		send("POST", "/v1/packs/", `{"size": 500}`, "add-250"),
+		send("POST", "/v1/catalogs/bolts/packs/", `{"size": 250}`, "add-250"),
+	} {
+		assert.Equal(t, http.StatusUnprocessableEntity, rr.Code)
+		var p Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+		assert.Equal(t, CodeIdempotencyKeyReused, p.Code)
+	}
+
+	for i := 0; i < 2; i++ {
+		rr := send("POST", "/v1/order", `{"items_ordered": 250}`, "order-1")
+		assert.Equal(t, http.StatusOK, rr.Code)
+	}
+
+	// Server errors are not stored, so the request can be retried.
+	assert.Equal(t, http.StatusInternalServerError, send("DELETE", "/v1/packs/500", "", "remove-500").Code)
+	assert.Equal(t, http.StatusNoContent, send("DELETE", "/v1/packs/500", "", "remove-500").Code)
+
+	rr := send("DELETE", "/v1/packs/500", "", strings.Repeat("k", 256))
+	assert.Equal(t, http.StatusBadRequest, rr.Code)
+}
+
+func TestIdempotent_InProgress(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	store := mocks.NewMockStoreInterface(ctrl)
+	store.EXPECT().Begin("ip:192.0.2.1 k1", gomock.Any()).Return(nil, idempotency.ErrInProgress).Times(1)
+
+	rr := httptest.NewRecorder()
+	req := httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 250}`))
+	req.Header.Set("Idempotency-Key", "k1")
+	New(mocks.NewMockRegistryInterface(ctrl), WithIdempotencyStore(store)).Idempotent(http.NotFoundHandler()).ServeHTTP(rr, req)
+
+	assert.Equal(t, http.StatusConflict, rr.Code)
+}
@@ -0,0 +1,76 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: store.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	idempotency "github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
+)
+
+// MockStoreInterface is a mock of StoreInterface interface.
+type MockStoreInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockStoreInterfaceMockRecorder
+}
+
+// MockStoreInterfaceMockRecorder is the mock recorder for MockStoreInterface.
+type MockStoreInterfaceMockRecorder struct {
+	mock *MockStoreInterface
+}
+
+// NewMockStoreInterface creates a new mock instance.
+func NewMockStoreInterface(ctrl *gomock.Controller) *MockStoreInterface {
+	mock := &MockStoreInterface{ctrl: ctrl}
+	mock.recorder = &MockStoreInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockStoreInterface) EXPECT() *MockStoreInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Abandon mocks base method.
+func (m *MockStoreInterface) Abandon(key string) {
+	m.ctrl.T.Helper()
+	m.ctrl.Call(m, "Abandon", key)
+}
+
+// Abandon indicates an expected call of Abandon.
+func (mr *MockStoreInterfaceMockRecorder) Abandon(key interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Abandon", reflect.TypeOf((*MockStoreInterface)(nil).Abandon), key)
+}
+
+// Begin mocks base method.
+func (m *MockStoreInterface) Begin(key, fingerprint string) (*idempotency.Response, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Begin", key, fingerprint)
+	ret0, _ := ret[0].(*idempotency.Response)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Begin indicates an expected call of Begin.
+func (mr *MockStoreInterfaceMockRecorder) Begin(key, fingerprint interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Begin", reflect.TypeOf((*MockStoreInterface)(nil).Begin), key, fingerprint)
+}
+
+// Complete mocks base method.
+func (m *MockStoreInterface) Complete(key, fingerprint string, res idempotency.Response) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Complete", key, fingerprint, res)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Complete indicates an expected call of Complete.
+func (mr *MockStoreInterfaceMockRecorder) Complete(key, fingerprint, res interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Complete", reflect.TypeOf((*MockStoreInterface)(nil).Complete), key, fingerprint, res)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: jwt.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	auth "github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+)
+
+// MockTokenVerifierInterface is a mock of TokenVerifierInterface interface.
+type MockTokenVerifierInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockTokenVerifierInterfaceMockRecorder
+}
+
+// MockTokenVerifierInterfaceMockRecorder is the mock recorder for MockTokenVerifierInterface.
+type MockTokenVerifierInterfaceMockRecorder struct {
+	mock *MockTokenVerifierInterface
+}
+
+// NewMockTokenVerifierInterface creates a new mock instance.
+func NewMockTokenVerifierInterface(ctrl *gomock.Controller) *MockTokenVerifierInterface {
+	mock := &MockTokenVerifierInterface{ctrl: ctrl}
+	mock.recorder = &MockTokenVerifierInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockTokenVerifierInterface) EXPECT() *MockTokenVerifierInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Verify mocks base method.
+func (m *MockTokenVerifierInterface) Verify(token string) (auth.Principal, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Verify", token)
+	ret0, _ := ret[0].(auth.Principal)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Verify indicates an expected call of Verify.
+func (mr *MockTokenVerifierInterfaceMockRecorder) Verify(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Verify", reflect.TypeOf((*MockTokenVerifierInterface)(nil).Verify), token)
+}
@@ -0,0 +1,95 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: keys.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	auth "github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+)
+
+// MockKeyStoreInterface is a mock of KeyStoreInterface interface.
+type MockKeyStoreInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockKeyStoreInterfaceMockRecorder
+}
+
+// MockKeyStoreInterfaceMockRecorder is the mock recorder for MockKeyStoreInterface.
+type MockKeyStoreInterfaceMockRecorder struct {
+	mock *MockKeyStoreInterface
+}
+
+// NewMockKeyStoreInterface creates a new mock instance.
+func NewMockKeyStoreInterface(ctrl *gomock.Controller) *MockKeyStoreInterface {
+	mock := &MockKeyStoreInterface{ctrl: ctrl}
+	mock.recorder = &MockKeyStoreInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockKeyStoreInterface) EXPECT() *MockKeyStoreInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Authenticate mocks base method.
+func (m *MockKeyStoreInterface) Authenticate(token string) (auth.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Authenticate", token)
+	ret0, _ := ret[0].(auth.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Authenticate indicates an expected call of Authenticate.
+func (mr *MockKeyStoreInterfaceMockRecorder) Authenticate(token interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Authenticate", reflect.TypeOf((*MockKeyStoreInterface)(nil).Authenticate), token)
+}
+
+// Create mocks base method.
+func (m *MockKeyStoreInterface) Create(name string, scopes []auth.Scope) (auth.APIKey, string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Create", name, scopes)
+	ret0, _ := ret[0].(auth.APIKey)
+	ret1, _ := ret[1].(string)
+	ret2, _ := ret[2].(error)
+	return ret0, ret1, ret2
+}
+
+// Create indicates an expected call of Create.
+func (mr *MockKeyStoreInterfaceMockRecorder) Create(name, scopes interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Create", reflect.TypeOf((*MockKeyStoreInterface)(nil).Create), name, scopes)
+}
+
+// List mocks base method.
+func (m *MockKeyStoreInterface) List() ([]auth.APIKey, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "List")
+	ret0, _ := ret[0].([]auth.APIKey)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// List indicates an expected call of List.
+func (mr *MockKeyStoreInterfaceMockRecorder) List() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "List", reflect.TypeOf((*MockKeyStoreInterface)(nil).List))
+}
+
+// Revoke mocks base method.
+func (m *MockKeyStoreInterface) Revoke(id string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Revoke", id)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// Revoke indicates an expected call of Revoke.
+func (mr *MockKeyStoreInterfaceMockRecorder) Revoke(id interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Revoke", reflect.TypeOf((*MockKeyStoreInterface)(nil).Revoke), id)
+}
@@ -0,0 +1,50 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: limiter.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	ratelimit "github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
+)
+
+// MockLimiterInterface is a mock of LimiterInterface interface.
+type MockLimiterInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockLimiterInterfaceMockRecorder
+}
+
+// MockLimiterInterfaceMockRecorder is the mock recorder for MockLimiterInterface.
+type MockLimiterInterfaceMockRecorder struct {
+	mock *MockLimiterInterface
+}
+
+// NewMockLimiterInterface creates a new mock instance.
+func NewMockLimiterInterface(ctrl *gomock.Controller) *MockLimiterInterface {
+	mock := &MockLimiterInterface{ctrl: ctrl}
+	mock.recorder = &MockLimiterInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockLimiterInterface) EXPECT() *MockLimiterInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Allow mocks base method.
+func (m *MockLimiterInterface) Allow(budget ratelimit.Budget, client string, cost int) (ratelimit.Decision, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Allow", budget, client, cost)
+	ret0, _ := ret[0].(ratelimit.Decision)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Allow indicates an expected call of Allow.
+func (mr *MockLimiterInterfaceMockRecorder) Allow(budget, client, cost interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Allow", reflect.TypeOf((*MockLimiterInterface)(nil).Allow), budget, client, cost)
+}
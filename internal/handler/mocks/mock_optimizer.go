@@ -9,6 +9,7 @@ import (
 
 	gomock "github.com/golang/mock/gomock"
 	optimizer "github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	sizer "github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 )
 
 // MockOptimizerInterface is a mock of OptimizerInterface interface.
@@ -34,6 +35,20 @@ func (m *MockOptimizerInterface) EXPECT() *MockOptimizerInterfaceMockRecorder {
 	return m.recorder
 }
 
+// AddPack mocks base method.
+func (m *MockOptimizerInterface) AddPack(pack sizer.Pack) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "AddPack", pack)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// AddPack indicates an expected call of AddPack.
+func (mr *MockOptimizerInterfaceMockRecorder) AddPack(pack interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "AddPack", reflect.TypeOf((*MockOptimizerInterface)(nil).AddPack), pack)
+}
+
 // AddSize mocks base method.
 func (m *MockOptimizerInterface) AddSize(size int) error {
 	m.ctrl.T.Helper()
@@ -49,17 +64,38 @@ func (mr *MockOptimizerInterfaceMockRecorder) AddSize(size interface{}) *gomock.
 }
 
 // Calculate mocks base method.
-func (m *MockOptimizerInterface) Calculate(itemsOrdered int) *optimizer.OptimizationResult {
+func (m *MockOptimizerInterface) Calculate(itemsOrdered int, options ...optimizer.CalculateOption) (*optimizer.OptimizationResult, error) {
 	m.ctrl.T.Helper()
-	ret := m.ctrl.Call(m, "Calculate", itemsOrdered)
+	varargs := []interface{}{itemsOrdered}
+	for _, a := range options {
+		varargs = append(varargs, a)
+	}
+	ret := m.ctrl.Call(m, "Calculate", varargs...)
 	ret0, _ := ret[0].(*optimizer.OptimizationResult)
-	return ret0
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
 }
 
 // Calculate indicates an expected call of Calculate.
-func (mr *MockOptimizerInterfaceMockRecorder) Calculate(itemsOrdered interface{}) *gomock.Call {
+func (mr *MockOptimizerInterfaceMockRecorder) Calculate(itemsOrdered interface{}, options ...interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	varargs := append([]interface{}{itemsOrdered}, options...)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calculate", reflect.TypeOf((*MockOptimizerInterface)(nil).Calculate), varargs...)
+}
+
+// GetAllPacks mocks base method.
+func (m *MockOptimizerInterface) GetAllPacks() ([]sizer.Pack, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetAllPacks")
+	ret0, _ := ret[0].([]sizer.Pack)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetAllPacks indicates an expected call of GetAllPacks.
+func (mr *MockOptimizerInterfaceMockRecorder) GetAllPacks() *gomock.Call {
 	mr.mock.ctrl.T.Helper()
-	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Calculate", reflect.TypeOf((*MockOptimizerInterface)(nil).Calculate), itemsOrdered)
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllPacks", reflect.TypeOf((*MockOptimizerInterface)(nil).GetAllPacks))
 }
 
 // GetAllSizes mocks base method.
@@ -77,6 +113,36 @@ func (mr *MockOptimizerInterfaceMockRecorder) GetAllSizes() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetAllSizes", reflect.TypeOf((*MockOptimizerInterface)(nil).GetAllSizes))
 }
 
+// GetVersion mocks base method.
+func (m *MockOptimizerInterface) GetVersion(number int) (sizer.Version, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersion", number)
+	ret0, _ := ret[0].(sizer.Version)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVersion indicates an expected call of GetVersion.
+func (mr *MockOptimizerInterfaceMockRecorder) GetVersion(number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersion", reflect.TypeOf((*MockOptimizerInterface)(nil).GetVersion), number)
+}
+
+// GetVersions mocks base method.
+func (m *MockOptimizerInterface) GetVersions() ([]sizer.Version, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "GetVersions")
+	ret0, _ := ret[0].([]sizer.Version)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// GetVersions indicates an expected call of GetVersions.
+func (mr *MockOptimizerInterfaceMockRecorder) GetVersions() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetVersions", reflect.TypeOf((*MockOptimizerInterface)(nil).GetVersions))
+}
+
 // Load mocks base method.
 func (m *MockOptimizerInterface) Load() error {
 	m.ctrl.T.Helper()
@@ -91,6 +157,20 @@ func (mr *MockOptimizerInterfaceMockRecorder) Load() *gomock.Call {
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Load", reflect.TypeOf((*MockOptimizerInterface)(nil).Load))
 }
 
+// MergePacks mocks base method.
+func (m *MockOptimizerInterface) MergePacks(packs []sizer.Pack) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "MergePacks", packs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// MergePacks indicates an expected call of MergePacks.
+func (mr *MockOptimizerInterfaceMockRecorder) MergePacks(packs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "MergePacks", reflect.TypeOf((*MockOptimizerInterface)(nil).MergePacks), packs)
+}
+
 // RemoveSize mocks base method.
 func (m *MockOptimizerInterface) RemoveSize(size int) error {
 	m.ctrl.T.Helper()
@@ -104,3 +184,59 @@ func (mr *MockOptimizerInterfaceMockRecorder) RemoveSize(size interface{}) *gomo
 	mr.mock.ctrl.T.Helper()
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RemoveSize", reflect.TypeOf((*MockOptimizerInterface)(nil).RemoveSize), size)
 }
+
+// ReplaceSizes mocks base method.
+func (m *MockOptimizerInterface) ReplaceSizes(packs []sizer.Pack) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ReplaceSizes", packs)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// ReplaceSizes indicates an expected call of ReplaceSizes.
+func (mr *MockOptimizerInterfaceMockRecorder) ReplaceSizes(packs interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ReplaceSizes", reflect.TypeOf((*MockOptimizerInterface)(nil).ReplaceSizes), packs)
+}
+
+// RestoreVersion mocks base method.
+func (m *MockOptimizerInterface) RestoreVersion(number int) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "RestoreVersion", number)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// RestoreVersion indicates an expected call of RestoreVersion.
+func (mr *MockOptimizerInterfaceMockRecorder) RestoreVersion(number interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "RestoreVersion", reflect.TypeOf((*MockOptimizerInterface)(nil).RestoreVersion), number)
+}
+
+// SetEnabled mocks base method.
+func (m *MockOptimizerInterface) SetEnabled(size int, enabled bool) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "SetEnabled", size, enabled)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// SetEnabled indicates an expected call of SetEnabled.
+func (mr *MockOptimizerInterfaceMockRecorder) SetEnabled(size, enabled interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "SetEnabled", reflect.TypeOf((*MockOptimizerInterface)(nil).SetEnabled), size, enabled)
+}
+
+// UpdatePack mocks base method.
+func (m *MockOptimizerInterface) UpdatePack(size int, update func(*sizer.Pack) error) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdatePack", size, update)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// UpdatePack indicates an expected call of UpdatePack.
+func (mr *MockOptimizerInterfaceMockRecorder) UpdatePack(size, update interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdatePack", reflect.TypeOf((*MockOptimizerInterface)(nil).UpdatePack), size, update)
+}
@@ -0,0 +1,64 @@
+// Code generated by MockGen. DO NOT EDIT.
+// Source: registry.go
+
+// Package mocks is a generated GoMock package.
+package mocks
+
+import (
+	reflect "reflect"
+
+	gomock "github.com/golang/mock/gomock"
+	optimizer "github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+)
+
+// MockRegistryInterface is a mock of RegistryInterface interface.
+type MockRegistryInterface struct {
+	ctrl     *gomock.Controller
+	recorder *MockRegistryInterfaceMockRecorder
+}
+
+// MockRegistryInterfaceMockRecorder is the mock recorder for MockRegistryInterface.
+type MockRegistryInterfaceMockRecorder struct {
+	mock *MockRegistryInterface
+}
+
+// NewMockRegistryInterface creates a new mock instance.
+func NewMockRegistryInterface(ctrl *gomock.Controller) *MockRegistryInterface {
+	mock := &MockRegistryInterface{ctrl: ctrl}
+	mock.recorder = &MockRegistryInterfaceMockRecorder{mock}
+	return mock
+}
+
+// EXPECT returns an object that allows the caller to indicate expected use.
+func (m *MockRegistryInterface) EXPECT() *MockRegistryInterfaceMockRecorder {
+	return m.recorder
+}
+
+// Get mocks base method.
+func (m *MockRegistryInterface) Get(catalog string) (optimizer.OptimizerInterface, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Get", catalog)
+	ret0, _ := ret[0].(optimizer.OptimizerInterface)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// Get indicates an expected call of Get.
+func (mr *MockRegistryInterfaceMockRecorder) Get(catalog interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Get", reflect.TypeOf((*MockRegistryInterface)(nil).Get), catalog)
+}
+
+// Stats mocks base method.
+func (m *MockRegistryInterface) Stats() map[string]optimizer.Stats {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "Stats")
+	ret0, _ := ret[0].(map[string]optimizer.Stats)
+	return ret0
+}
+
+// Stats indicates an expected call of Stats.
+func (mr *MockRegistryInterfaceMockRecorder) Stats() *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "Stats", reflect.TypeOf((*MockRegistryInterface)(nil).Stats))
+}
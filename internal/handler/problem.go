@@ -0,0 +1,220 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+)
+
+// problemContentType is the media type of every error response.
+const problemContentType = "application/problem+json"
+
+// problemTypeBase prefixes the code of a problem to form its type URI.
+const problemTypeBase = "/problems/"
+
+// Codes identifying each kind of problem. They are part of the API and never change once
+// released; new kinds of failure get new codes.
+const (
+	CodeInvalidRequest       = "invalid-request"
+	CodeInvalidCatalog       = "invalid-catalog"
+	CodeInvalidQuantity      = "invalid-quantity"
+	CodeQuantityTooLarge     = "quantity-too-large"
+	CodeUnknownFormat        = "unknown-format"
+	CodePayloadTooLarge      = "payload-too-large"
+	CodeUnauthorized         = "unauthorized"
+	CodeForbidden            = "forbidden"
+	CodeNotFound             = "not-found"
+	CodeMethodNotAllowed     = "method-not-allowed"
+	CodeSizeNotFound         = "size-not-found"
+	CodeVersionNotFound      = "version-not-found"
+	CodeKeyNotFound          = "key-not-found"
+	CodeNoSizes              = "no-sizes"
+	CodeInsufficientStock    = "insufficient-stock"
+	CodeInfeasible           = "infeasible"
+	CodeAlternativesTooLarge = "alternatives-too-large"
+	CodeInvalidRecords       = "invalid-records"
+	CodeRateLimited          = "rate-limited"
+	CodeIdempotencyKeyReused = "idempotency-key-reused"
+	CodeIdempotencyKeyInUse  = "idempotency-key-in-use"
+	CodeInternal             = "internal-error"
+)
+
+// problemKind is the status and title shared by every problem of a code.
+type problemKind struct {
+	status int
+	title  string
+}
+
+// problemKinds holds the status and title of every problem code.
+var problemKinds = map[string]problemKind{
+	CodeInvalidRequest:       {http.StatusBadRequest, "Invalid request"},
+	CodeInvalidCatalog:       {http.StatusBadRequest, "Invalid catalog"},
+	CodeInvalidQuantity:      {http.StatusBadRequest, "Invalid quantity"},
+	CodeQuantityTooLarge:     {http.StatusBadRequest, "Quantity too large"},
+	CodeUnknownFormat:        {http.StatusBadRequest, "Unknown format"},
+	CodePayloadTooLarge:      {http.StatusRequestEntityTooLarge, "Payload too large"},
+	CodeUnauthorized:         {http.StatusUnauthorized, "Unauthorized"},
+	CodeForbidden:            {http.StatusForbidden, "Forbidden"},
+	CodeNotFound:             {http.StatusNotFound, "Not found"},
+	CodeMethodNotAllowed:     {http.StatusMethodNotAllowed, "Method not allowed"},
+	CodeSizeNotFound:         {http.StatusNotFound, "Pack size not found"},
+	CodeVersionNotFound:      {http.StatusNotFound, "Catalog version not found"},
+	CodeKeyNotFound:          {http.StatusNotFound, "API key not found"},
+	CodeNoSizes:              {http.StatusConflict, "No pack sizes configured"},
+	CodeInsufficientStock:    {http.StatusConflict, "Insufficient stock"},
+	CodeInfeasible:           {http.StatusUnprocessableEntity, "Order cannot be satisfied"},
+	CodeAlternativesTooLarge: {http.StatusUnprocessableEntity, "Order too large to list alternatives"},
+	CodeInvalidRecords:       {http.StatusUnprocessableEntity, "Invalid records"},
+	CodeRateLimited:          {http.StatusTooManyRequests, "Too many requests"},
+	CodeIdempotencyKeyReused: {http.StatusUnprocessableEntity, "Idempotency key reused"},
+	CodeIdempotencyKeyInUse:  {http.StatusConflict, "Idempotency key in use"},
+	CodeInternal:             {http.StatusInternalServerError, "Internal server error"},
+}
+
+// Problem is an RFC 7807 problem details object, the body of every error response. Code
+// repeats the last segment of Type for clients that switch on it, Field names the request
+// field at fault, if any, and Errors lists the records rejected by an import.
+type Problem struct {
+	Type      string            `json:"type"`
+	Title     string            `json:"title"`
+	Status    int               `json:"status"`
+	Detail    string            `json:"detail,omitempty"`
+	Instance  string            `json:"instance,omitempty"`
+	Code      string            `json:"code"`
+	Field     string            `json:"field,omitempty"`
+	RequestID string            `json:"request_id,omitempty"`
+	Errors    []sizer.LineError `json:"errors,omitempty"`
+}
+
+// newProblem returns the problem of code described by detail.
+func newProblem(code, detail string) Problem {
+	kind, ok := problemKinds[code]
+	if !ok {
+		code, kind = CodeInternal, problemKinds[CodeInternal]
+	}
+	return Problem{
+		Type:   problemTypeBase + code,
+		Title:  kind.title,
+		Status: kind.status,
+		Detail: detail,
+		Code:   code,
+	}
+}
+
+// at returns p blaming field of the request.
+func (p Problem) at(field string) Problem {
+	p.Field = field
+	return p
+}
+
+// invalidField returns the invalid-request problem of field described by detail.
+func invalidField(field, detail string) Problem {
+	return newProblem(CodeInvalidRequest, detail).at(field)
+}
+
+// bodyProblem returns the problem of a request body that could not be read or decoded,
+// blaming the field of the wrong type if that is why.
+func bodyProblem(err error) Problem {
+	var (
+		maxBytesErr *http.MaxBytesError
+		typeErr     *json.UnmarshalTypeError
+	)
+	if errors.As(err, &maxBytesErr) {
+		return newProblem(CodePayloadTooLarge, "request body is too large")
+	}
+	p := newProblem(CodeInvalidRequest, "Invalid request body")
+	if errors.As(err, &typeErr) {
+		p.Field = typeErr.Field
+	}
+	return p
+}
+
+// errorProblem maps an error returned by the sizer, the optimizer or auth to its problem. Errors
+// it does not know are internal errors, described by fallback rather than by their own
+// text so that no internal detail leaks.
+func errorProblem(err error, fallback string) Problem {
+	var (
+		fieldErr  *sizer.FieldError
+		importErr *sizer.ImportError
+	)
+	switch {
+	case errors.As(err, &fieldErr):
+		return invalidField(fieldErr.Field, fieldErr.Message)
+	case errors.Is(err, auth.ErrInvalidScope):
+		return invalidField("scopes", err.Error())
+	case errors.As(err, &importErr):
+		p := newProblem(CodeInvalidRecords, "invalid records, nothing was imported")
+		p.Errors = importErr.Lines
+		return p
+	case errors.Is(err, optimizer.ErrInvalidQuantity):
+		return newProblem(CodeInvalidQuantity, err.Error()).at("items_ordered")
+	case errors.Is(err, optimizer.ErrQuantityTooLarge):
+		return newProblem(CodeQuantityTooLarge, err.Error()).at("items_ordered")
+	}
+
+	for _, e := range errorCodes {
+		if errors.Is(err, e.target) {
+			return newProblem(e.code, err.Error())
+		}
+	}
+	return newProblem(CodeInternal, fallback)
+}
+
+// errorCodes maps the sentinel errors of the sizer, the optimizer and auth to problem
+// codes.
+var errorCodes = []struct {
+	target error
+	code   string
+}{
+	{sizer.ErrInvalidCatalog, CodeInvalidCatalog},
+	{sizer.ErrUnknownFormat, CodeUnknownFormat},
+	{sizer.ErrSizeNotFound, CodeSizeNotFound},
+	{sizer.ErrVersionNotFound, CodeVersionNotFound},
+	{auth.ErrInvalidKey, CodeUnauthorized},
+	{auth.ErrInvalidToken, CodeUnauthorized},
+	{auth.ErrKeyNotFound, CodeKeyNotFound},
+	{idempotency.ErrKeyReused, CodeIdempotencyKeyReused},
+	{idempotency.ErrInProgress, CodeIdempotencyKeyInUse},
+	{optimizer.ErrNoSizes, CodeNoSizes},
+	{optimizer.ErrInsufficientStock, CodeInsufficientStock},
+	{optimizer.ErrInfeasible, CodeInfeasible},
+	{optimizer.ErrAlternativesTooLarge, CodeAlternativesTooLarge},
+}
+
+// writeProblem writes p as the response to r, identified by the request path and ID.
+func writeProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Instance = r.URL.Path
+	p.RequestID = middleware.GetReqID(r.Context())
+
+	w.Header().Set("Content-Type", problemContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
+
+// writeError writes the problem err maps to, described by fallback when it is an
+// internal error.
+func writeError(w http.ResponseWriter, r *http.Request, err error, fallback string) {
+	writeProblem(w, r, errorProblem(err, fallback))
+}
+
+// exposeRequestID returns the ID given to each request by middleware.RequestID in the
+// X-Request-Id header of its response.
+func exposeRequestID(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if id := middleware.GetReqID(r.Context()); id != "" {
+			w.Header().Set(middleware.RequestIDHeader, id)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// methodNotAllowed answers requests with a method a route does not support.
+func methodNotAllowed(w http.ResponseWriter, r *http.Request) {
+	writeProblem(w, r, newProblem(CodeMethodNotAllowed, r.Method+" is not supported on this route"))
+}
@@ -0,0 +1,117 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/idempotency"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestErrorProblem(t *testing.T) {
+	tests := []struct {
+		err    error
+		status int
+		code   string
+		field  string
+	}{
+		{err: optimizer.ErrInvalidQuantity, status: http.StatusBadRequest, code: CodeInvalidQuantity, field: "items_ordered"},
+		{err: optimizer.ErrQuantityTooLarge, status: http.StatusBadRequest, code: CodeQuantityTooLarge, field: "items_ordered"},
+		{err: sizer.ErrInvalidCatalog, status: http.StatusBadRequest, code: CodeInvalidCatalog},
+		{err: sizer.ErrUnknownFormat, status: http.StatusBadRequest, code: CodeUnknownFormat},
+		{err: &sizer.FieldError{Field: "price", Message: "price must not be negative"}, status: http.StatusBadRequest, code: CodeInvalidRequest, field: "price"},
+		{err: fmt.Errorf("wrapped: %w", sizer.ErrSizeNotFound), status: http.StatusNotFound, code: CodeSizeNotFound},
+		{err: sizer.ErrVersionNotFound, status: http.StatusNotFound, code: CodeVersionNotFound},
+		{err: optimizer.ErrNoSizes, status: http.StatusConflict, code: CodeNoSizes},
+		{err: optimizer.ErrInsufficientStock, status: http.StatusConflict, code: CodeInsufficientStock},
+		{err: optimizer.ErrInfeasible, status: http.StatusUnprocessableEntity, code: CodeInfeasible},
+		{err: optimizer.ErrAlternativesTooLarge, status: http.StatusUnprocessableEntity, code: CodeAlternativesTooLarge},
+		{err: &sizer.ImportError{}, status: http.StatusUnprocessableEntity, code: CodeInvalidRecords},
+		{err: idempotency.ErrKeyReused, status: http.StatusUnprocessableEntity, code: CodeIdempotencyKeyReused},
+		{err: idempotency.ErrInProgress, status: http.StatusConflict, code: CodeIdempotencyKeyInUse},
+		{err: fmt.Errorf("%w: token has expired", auth.ErrInvalidToken), status: http.StatusUnauthorized, code: CodeUnauthorized},
+		{err: errors.New("disk on fire"), status: http.StatusInternalServerError, code: CodeInternal},
+	}
+	for _, test := range tests {
+		p := errorProblem(test.err, "fallback")
+		assert.Equal(t, test.status, p.Status, test.err)
+		assert.Equal(t, test.code, p.Code, test.err)
+		assert.Equal(t, "/problems/"+test.code, p.Type, test.err)
+		assert.Equal(t, test.field, p.Field, test.err)
+		assert.NotEmpty(t, p.Title, test.err)
+	}
+
+	assert.Equal(t, "fallback", errorProblem(errors.New("disk on fire"), "fallback").Detail)
+}
+
+func TestBodyProblem(t *testing.T) {
+	var v struct {
+		Size int `json:"size"`
+	}
+	p := bodyProblem(json.Unmarshal([]byte(`{"size": "x"}`), &v))
+	assert.Equal(t, http.StatusBadRequest, p.Status)
+	assert.Equal(t, "size", p.Field)
+
+	p = bodyProblem(&http.MaxBytesError{Limit: 1})
+	assert.Equal(t, http.StatusRequestEntityTooLarge, p.Status)
+	assert.Equal(t, CodePayloadTooLarge, p.Code)
+}
+
+func TestProblemResponses(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().GetAllPacks().Return(nil, errors.New("disk on fire")).Times(1)
+
+	router, err := NewRouter(newTestHandler(ctrl, mockOptimizer))
+	require.NoError(t, err)
+
+	tests := []struct {
+		method, target, body string
+		status               int
+		code, field          string
+	}{
+		{method: "GET", target: "/v1/packs/", status: http.StatusInternalServerError, code: CodeInternal},
+		{method: "POST", target: "/v1/packs/", body: `{"size": 10, "price": -1}`, status: http.StatusBadRequest, code: CodeInvalidRequest, field: "price"},
+		{method: "PUT", target: "/v1/packs/", body: `[250, {"size": 500, "stock": -1}]`, status: http.StatusBadRequest, code: CodeInvalidRequest, field: "[1].stock"},
+		{method: "DELETE", target: "/v1/packs/abc", status: http.StatusBadRequest, code: CodeInvalidRequest, field: "size"},
+		{method: "POST", target: "/v1/order", body: `{"items_ordered": 0}`, status: http.StatusBadRequest, code: CodeInvalidQuantity, field: "items_ordered"},
+		{method: "POST", target: "/v1/order", body: `{"items_ordered": "ten"}`, status: http.StatusBadRequest, code: CodeInvalidRequest, field: "items_ordered"},
+		{method: "POST", target: "/v1/order", body: `{"items_ordered": 10, "strategy": "weighted", "pack_weight": -1}`, status: http.StatusBadRequest, code: CodeInvalidRequest, field: "pack_weight"},
+		{method: "GET", target: "/v1/packs/export?format=xml", status: http.StatusBadRequest, code: CodeUnknownFormat, field: "format"},
+		{method: "GET", target: "/v1/nowhere", status: http.StatusNotFound, code: CodeNotFound},
+		{method: "PATCH", target: "/v1/order", status: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed},
+	}
+	for _, test := range tests {
+		name := test.method + " " + test.target
+		req := httptest.NewRequest(test.method, test.target, strings.NewReader(test.body))
+		req.Header.Set("X-Request-Id", "req-42")
+		rr := httptest.NewRecorder()
+
+		router.ServeHTTP(rr, req)
+
+		assert.Equal(t, test.status, rr.Code, name)
+		assert.Equal(t, "application/problem+json", rr.Header().Get("Content-Type"), name)
+		assert.Equal(t, "req-42", rr.Header().Get("X-Request-Id"), name)
+
+		var p Problem
+		require.NoError(t, json.NewDecoder(rr.Body).Decode(&p), name)
+		assert.Equal(t, test.status, p.Status, name)
+		assert.Equal(t, test.code, p.Code, name)
+		assert.Equal(t, test.field, p.Field, name)
+		assert.Equal(t, req.URL.Path, p.Instance, name)
+		assert.Equal(t, "req-42", p.RequestID, name)
+	}
+}
@@ -0,0 +1,88 @@
+package handler
+
+import (
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
+)
+
+// Headers telling clients about their rate limit budget.
+var rateLimitHeaders = []string{"RateLimit-Limit", "RateLimit-Remaining", "RateLimit-Reset", "RateLimit-Policy", "Retry-After"}
+
+// WithRateLimiter limits the requests each client makes with limiter. Without it requests
+// are not limited.
+func WithRateLimiter(limiter ratelimit.LimiterInterface) Option {
+	return func(h *Handler) {
+		h.limiter = limiter
+	}
+}
+
+// RateLimit returns the middleware taking each request from the budget of its client,
+// and answering with 429 the requests of clients that have used it up. Responses carry
+// the RateLimit-* headers of the budget, and 429 ones a Retry-After header. Clients are
+// told apart by their auth.Principal, so it must run after Authenticate, or by their IP
+// address when not authenticated.
+func (h *Handler) RateLimit(budget ratelimit.Budget) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		if h.limiter == nil {
+			return next
+		}
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if h.allow(w, r, budget, 1) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allow takes cost requests of r from the budget of its client, setting the RateLimit-*
+// headers of the budget. It answers r itself and returns false when the client may not
+// make them.
+func (h *Handler) allow(w http.ResponseWriter, r *http.Request, budget ratelimit.Budget, cost int) bool {
+	if h.limiter == nil {
+		return true
+	}
+	decision, err := h.limiter.Allow(budget, clientOf(r), cost)
+	if err != nil {
+		writeError(w, r, err, "Failed to check the rate limit")
+		return false
+	}
+
+	if !decision.Limit.Unlimited() {
+		header := w.Header()
+		header.Set("RateLimit-Limit", strconv.Itoa(decision.Limit.Requests))
+		header.Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		header.Set("RateLimit-Reset", ceilSeconds(decision.Reset))
+		header.Set("RateLimit-Policy", strconv.Itoa(decision.Limit.Requests)+";w="+ceilSeconds(decision.Limit.Period))
+	}
+	if !decision.Allowed {
+		w.Header().Set("Retry-After", ceilSeconds(decision.RetryAfter))
+		writeProblem(w, r, newProblem(CodeRateLimited, "rate limit of "+decision.Limit.String()+" "+string(budget)+" requests exceeded"))
+		return false
+	}
+	return true
+}
+
+// clientOf returns the identity r is rate limited by: the subject of its principal, or
+// its IP address when it has none. Forwarding headers are ignored since any client can
+// set them.
+func clientOf(r *http.Request) string {
+	if principal, ok := auth.PrincipalFrom(r.Context()); ok {
+		return principal.Subject
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		host = r.RemoteAddr
+	}
+	return "ip:" + host
+}
+
+// ceilSeconds returns d in whole seconds, rounded up, as header values are.
+func ceilSeconds(d time.Duration) string {
+	return strconv.Itoa(int(math.Ceil(d.Seconds())))
+}
@@ -0,0 +1,116 @@
+package handler
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/jmsilvadev/go-pack-optimizer/internal/handler/mocks"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestRateLimit(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().GetAllPacks().Return([]sizer.Pack{sizer.NewPack(250)}, nil).AnyTimes()
+	mockOptimizer.EXPECT().Calculate(250).Return(&optimizer.OptimizationResult{PacksUsed: []int{250}}, nil).AnyTimes()
+	mockOptimizer.EXPECT().RemoveSize(250).Return(nil).AnyTimes()
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(mockOptimizer, nil).AnyTimes()
+
+	keys := mocks.NewMockKeyStoreInterface(ctrl)
+	keys.EXPECT().Authenticate("admin").Return(auth.APIKey{ID: "a", Scopes: []auth.Scope{auth.ScopeAdmin}}, nil).AnyTimes()
+
+	limit := ratelimit.Limit{Requests: 10, Period: time.Minute}
+	limiter := mocks.NewMockLimiterInterface(ctrl)
+	limiter.EXPECT().Allow(ratelimit.BudgetCalculate, "ip:192.0.2.1", 1).Return(ratelimit.Decision{Allowed: true, Limit: limit, Remaining: 9, Reset: 6 * time.Second}, nil).Times(1)
+	limiter.EXPECT().Allow(ratelimit.BudgetMutate, "apikey:a", 1).Return(ratelimit.Decision{Limit: limit, Reset: time.Minute, RetryAfter: 5500 * time.Millisecond}, nil).Times(1)
+	limiter.EXPECT().Allow(ratelimit.BudgetCalculate, "apikey:a", 1).Return(ratelimit.Decision{}, errors.New("disk on fire")).Times(1)
+
+	// Without API keys the client is told apart by its IP address.
+	router, err := NewRouter(New(registry, WithRateLimiter(limiter)))
+	require.NoError(t, err)
+
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 250}`)))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "10", rr.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "9", rr.Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "6", rr.Header().Get("RateLimit-Reset"))
+	assert.Equal(t, "10;w=60", rr.Header().Get("RateLimit-Policy"))
+	assert.Empty(t, rr.Header().Get("Retry-After"))
+
+	// Reading pack sizes is not limited.
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, httptest.NewRequest("GET", "/v1/packs/", nil))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Empty(t, rr.Header().Get("RateLimit-Limit"))
+
+	// With API keys the client is told apart by its key.
+	router, err = NewRouter(New(registry, WithKeyStore(keys), WithRateLimiter(limiter)))
+	require.NoError(t, err)
+
+	req := httptest.NewRequest("DELETE", "/v1/packs/250", nil)
+	req.Header.Set("X-API-Key", "admin")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.Equal(t, "6", rr.Header().Get("Retry-After"))
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+	var p Problem
+	require.NoError(t, json.NewDecoder(rr.Body).Decode(&p))
+	assert.Equal(t, CodeRateLimited, p.Code)
+
+	req = httptest.NewRequest("POST", "/v1/order", strings.NewReader(`{"items_ordered": 250}`))
+	req.Header.Set("X-API-Key", "admin")
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, req)
+	assert.Equal(t, http.StatusInternalServerError, rr.Code)
+}
+
+func TestRateLimit_Batch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockOptimizer := mocks.NewMockOptimizerInterface(ctrl)
+	mockOptimizer.EXPECT().Calculate(250).Return(&optimizer.OptimizationResult{PacksUsed: []int{250}}, nil).Times(3)
+	registry := mocks.NewMockRegistryInterface(ctrl)
+	registry.EXPECT().Get(gomock.Any()).Return(mockOptimizer, nil).AnyTimes()
+
+	limiter := ratelimit.NewLimiter(map[ratelimit.Budget]ratelimit.Limit{ratelimit.BudgetCalculate: {Requests: 4, Period: time.Hour}})
+	router, err := NewRouter(New(registry, WithRateLimiter(limiter)))
+	require.NoError(t, err)
+
+	batch := func(lines int) *http.Request {
+		body := `{"lines": [{"items_ordered": 250}` + strings.Repeat(`, {"items_ordered": 250}`, lines-1) + `]}`
+		return httptest.NewRequest("POST", "/v1/orders/batch", strings.NewReader(body))
+	}
+
+	// A batch over the budget is refused without calculating any line.
+	rr := httptest.NewRecorder()
+	router.ServeHTTP(rr, batch(5))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+	assert.NotEmpty(t, rr.Header().Get("Retry-After"))
+
+	// The refused batch was charged its first line only, leaving three.
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, batch(3))
+	assert.Equal(t, http.StatusOK, rr.Code)
+	assert.Equal(t, "0", rr.Header().Get("RateLimit-Remaining"))
+
+	rr = httptest.NewRecorder()
+	router.ServeHTTP(rr, batch(1))
+	assert.Equal(t, http.StatusTooManyRequests, rr.Code)
+}
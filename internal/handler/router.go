@@ -5,11 +5,17 @@ import (
 	"net/http"
 
 	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-chi/cors"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/ratelimit"
 )
 
 // NewRouter creates and returns a new HTTP router with all defined routes.
-// It connects HTTP endpoints to their respective handler functions.
+// It connects HTTP endpoints to their respective handler functions. Every route but
+// /health goes through the handler's authentication and requires its own scope. Order
+// calculations take from the calculate rate limit budget and changes from the mutate one.
+// Orders and adding or removing pack sizes honour the Idempotency-Key header.
 func NewRouter(h HandlerInterface) (http.Handler, error) {
 	if h == nil {
 		return nil, fmt.Errorf("invalid handler")
@@ -17,24 +23,73 @@ func NewRouter(h HandlerInterface) (http.Handler, error) {
 
 	r := chi.NewRouter()
 
+	r.Use(middleware.RequestID, exposeRequestID)
 	r.Use(cors.Handler(cors.Options{
 		AllowedOrigins:   []string{"*"},
-		AllowedMethods:   []string{"GET", "POST", "DELETE", "OPTIONS"},
-		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token"},
+		AllowedMethods:   []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowedHeaders:   []string{"Accept", "Authorization", "Content-Type", "X-CSRF-Token", middleware.RequestIDHeader, apiKeyHeader, idempotencyKeyHeader},
+		ExposedHeaders:   append([]string{middleware.RequestIDHeader, idempotentReplayedHeader}, rateLimitHeaders...),
 		AllowCredentials: true,
 	}))
 
 	r.Get("/health", h.HealthHandler)
 
-	r.Route("/v1/packs", func(r chi.Router) {
-		r.Get("/", h.GetPacks)
-		r.Post("/", h.PostPacks)
-		r.Delete("/{size}", h.DeletePacks)
-	})
+	r.Group(func(r chi.Router) {
+		r.Use(h.Authenticate)
+
+		r.Route("/v1/packs", func(r chi.Router) {
+			packRoutes(r, h)
+		})
+
+		r.Group(func(r chi.Router) {
+			r.Use(h.Require(auth.ScopeOrderCalculate), h.RateLimit(ratelimit.BudgetCalculate))
+			r.With(h.Idempotent).Post("/v1/order", h.CalculateOrder)
+			r.Post("/v1/orders/batch", h.CalculateBatch)
+		})
 
-	r.Post("/v1/order", h.CalculateOrder)
+		r.Route("/v1/catalogs/{id}", func(r chi.Router) {
+			r.Route("/packs", func(r chi.Router) {
+				packRoutes(r, h)
+			})
+			r.With(h.Require(auth.ScopeOrderCalculate), h.RateLimit(ratelimit.BudgetCalculate), h.Idempotent).Post("/order", h.CalculateOrder)
+		})
+
+		r.With(h.Require(auth.ScopeAdmin)).Get("/v1/admin/cache", h.CacheStats)
+
+		r.Route("/v1/admin/keys", func(r chi.Router) {
+			r.Use(h.Require(auth.ScopeAdmin))
+			r.Get("/", h.ListKeys)
+			r.With(h.RateLimit(ratelimit.BudgetMutate)).Post("/", h.CreateKey)
+			r.With(h.RateLimit(ratelimit.BudgetMutate)).Delete("/{key}", h.RevokeKey)
+		})
+	})
 
 	r.NotFound(h.NotFoundHandler)
+	r.MethodNotAllowed(methodNotAllowed)
 
 	return r, nil
 }
+
+// packRoutes registers on r the pack size endpoints of a catalog. Reading them requires
+// the packs:read scope, and changing them packs:write and takes from the mutate budget.
+func packRoutes(r chi.Router, h HandlerInterface) {
+	r.Group(func(r chi.Router) {
+		r.Use(h.Require(auth.ScopePacksRead))
+		r.Get("/", h.GetPacks)
+		r.Get("/versions", h.GetVersions)
+		r.Get("/versions/{v}", h.GetVersion)
+		r.Get("/export", h.ExportPacks)
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Use(h.Require(auth.ScopePacksWrite), h.RateLimit(ratelimit.BudgetMutate))
+		r.With(h.Idempotent).Post("/", h.PostPacks)
+		r.Put("/", h.PutPacks)
+		r.Patch("/{size}", h.PatchPacks)
+		r.With(h.Idempotent).Delete("/{size}", h.DeletePacks)
+		r.Post("/{size}/enable", h.EnablePacks)
+		r.Post("/{size}/disable", h.DisablePacks)
+		r.Post("/versions/{v}/restore", h.RestoreVersion)
+		r.Post("/import", h.ImportPacks)
+	})
+}
@@ -0,0 +1,214 @@
+package auth
+
+import (
+	"bytes"
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+)
+
+// Minimum strength of the keys ParseJWTKeys accepts.
+const (
+	minHMACSecret = 32
+	minRSABits    = 2048
+)
+
+// ErrNoKeys is returned by ParseJWTKeys when it finds no key it can verify tokens with.
+var ErrNoKeys = errors.New("no usable JWT verification key")
+
+// JWTKey is a key verifying the signatures of JWTs signed with one algorithm. Keys read from
+// a JWKS document have the ID of their kid member.
+type JWTKey struct {
+	id  string
+	alg string
+	key interface{}
+}
+
+// ReadJWTKeys reads the JWT verification keys of the file at path with ParseJWTKeys.
+func ReadJWTKeys(path string) ([]JWTKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	keys, err := ParseJWTKeys(data)
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return keys, nil
+}
+
+// ParseJWTKeys parses JWT verification keys from data, which holds one of:
+//   - a JWKS document, or a single JWK, with RSA, Ed25519 (OKP) or HMAC (oct) keys;
+//   - PEM encoded RSA or Ed25519 public keys or certificates;
+//   - an HMAC secret of at least 32 bytes, surrounding white space being ignored.
+//
+// Keys of a JWKS document meant for encryption or for other algorithms are skipped.
+func ParseJWTKeys(data []byte) ([]JWTKey, error) {
+	data = bytes.TrimSpace(data)
+	var keys []JWTKey
+	var err error
+	switch {
+	case bytes.HasPrefix(data, []byte("{")):
+		keys, err = parseJWKS(data)
+	case bytes.Contains(data, []byte("-----BEGIN")):
+		keys, err = parsePEMKeys(data)
+	default:
+		if len(data) < minHMACSecret {
+			return nil, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecret)
+		}
+		keys = []JWTKey{{alg: AlgHS256, key: data}}
+	}
+	if err != nil {
+		return nil, err
+	}
+	if len(keys) == 0 {
+		return nil, ErrNoKeys
+	}
+	return keys, nil
+}
+
+// jwk is a JSON Web Key, with the members of the key types ParseJWTKeys supports.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Alg string `json:"alg"`
+	Use string `json:"use"`
+	Crv string `json:"crv"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+	X   string `json:"x"`
+	K   string `json:"k"`
+}
+
+// parseJWKS parses a JWKS document, or a single JWK.
+func parseJWKS(data []byte) ([]JWTKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS document: %v", err)
+	}
+	if set.Keys == nil {
+		var single jwk
+		if err := json.Unmarshal(data, &single); err != nil {
+			return nil, fmt.Errorf("invalid JWK: %v", err)
+		}
+		set.Keys = []jwk{single}
+	}
+
+	keys := []JWTKey{}
+	for i, k := range set.Keys {
+		if k.Use != "" && k.Use != "sig" {
+			continue
+		}
+		key, ok, err := k.parse()
+		if err != nil {
+			return nil, fmt.Errorf("key %d: %w", i, err)
+		}
+		if ok {
+			keys = append(keys, key)
+		}
+	}
+	return keys, nil
+}
+
+// parse returns the key k describes, or false for a key of another type or algorithm.
+func (k jwk) parse() (JWTKey, bool, error) {
+	key := JWTKey{id: k.Kid}
+	switch {
+	case k.Kty == "oct":
+		secret, err := base64.RawURLEncoding.DecodeString(k.K)
+		if err != nil {
+			return JWTKey{}, false, errors.New("invalid k")
+		}
+		if len(secret) < minHMACSecret {
+			return JWTKey{}, false, fmt.Errorf("HMAC secret must be at least %d bytes", minHMACSecret)
+		}
+		key.alg, key.key = AlgHS256, secret
+	case k.Kty == "RSA":
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return JWTKey{}, false, errors.New("invalid RSA key")
+		}
+		pub := &rsa.PublicKey{N: new(big.Int).SetBytes(n), E: int(new(big.Int).SetBytes(e).Int64())}
+		if err := checkRSAKey(pub); err != nil {
+			return JWTKey{}, false, err
+		}
+		key.alg, key.key = AlgRS256, pub
+	case k.Kty == "OKP" && k.Crv == "Ed25519":
+		x, err := base64.RawURLEncoding.DecodeString(k.X)
+		if err != nil || len(x) != ed25519.PublicKeySize {
+			return JWTKey{}, false, errors.New("invalid Ed25519 key")
+		}
+		key.alg, key.key = AlgEdDSA, ed25519.PublicKey(x)
+	default:
+		return JWTKey{}, false, nil
+	}
+	if k.Alg != "" && k.Alg != key.alg {
+		return JWTKey{}, false, nil
+	}
+	return key, true, nil
+}
+
+// parsePEMKeys parses the PEM encoded public keys and certificates of data.
+func parsePEMKeys(data []byte) ([]JWTKey, error) {
+	keys := []JWTKey{}
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+
+		var pub interface{}
+		var err error
+		switch block.Type {
+		case "PUBLIC KEY":
+			pub, err = x509.ParsePKIXPublicKey(block.Bytes)
+		case "RSA PUBLIC KEY":
+			pub, err = x509.ParsePKCS1PublicKey(block.Bytes)
+		case "CERTIFICATE":
+			var cert *x509.Certificate
+			if cert, err = x509.ParseCertificate(block.Bytes); err == nil {
+				pub = cert.PublicKey
+			}
+		default:
+			return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid %s: %v", block.Type, err)
+		}
+
+		switch pub := pub.(type) {
+		case *rsa.PublicKey:
+			if err := checkRSAKey(pub); err != nil {
+				return nil, err
+			}
+			keys = append(keys, JWTKey{alg: AlgRS256, key: pub})
+		case ed25519.PublicKey:
+			keys = append(keys, JWTKey{alg: AlgEdDSA, key: pub})
+		default:
+			return nil, fmt.Errorf("unsupported %T public key", pub)
+		}
+	}
+	return keys, nil
+}
+
+// checkRSAKey rejects RSA keys too weak to trust.
+func checkRSAKey(pub *rsa.PublicKey) error {
+	if pub.N.BitLen() < minRSABits {
+		return fmt.Errorf("RSA keys must have at least %d bits", minRSABits)
+	}
+	if pub.E < 3 || pub.E%2 == 0 {
+		return errors.New("invalid RSA exponent")
+	}
+	return nil
+}
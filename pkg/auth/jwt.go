@@ -0,0 +1,210 @@
+package auth
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/rsa"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+	"time"
+)
+
+//go:generate mockgen -source=jwt.go -destination=../../internal/handler/mocks/mock_jwt.go -package=mocks
+
+// Signature algorithms of the JWTs a JWTVerifier accepts.
+const (
+	AlgHS256 = "HS256"
+	AlgRS256 = "RS256"
+	AlgEdDSA = "EdDSA"
+)
+
+// clockSkew is how far the clocks of the token issuer and this service may drift apart
+// when checking the exp and nbf claims.
+const clockSkew = 30 * time.Second
+
+// ErrInvalidToken is returned by Verify for a malformed, forged, expired or misaddressed
+// token. The reason is wrapped with it.
+var ErrInvalidToken = errors.New("invalid token")
+
+// TokenVerifierInterface verifies the bearer tokens clients authenticate with.
+type TokenVerifierInterface interface {
+	Verify(token string) (Principal, error)
+}
+
+// JWTVerifier verifies JWTs signed with one of its keys and issued for its audience.
+type JWTVerifier struct {
+	keys     []JWTKey
+	audience string
+	now      func() time.Time
+}
+
+// NewJWTVerifier returns a JWTVerifier accepting the tokens signed with keys whose aud
+// claim holds audience.
+func NewJWTVerifier(keys []JWTKey, audience string) *JWTVerifier {
+	return &JWTVerifier{
+		keys:     keys,
+		audience: audience,
+		now:      time.Now,
+	}
+}
+
+// jwtHeader is the JOSE header of a JWT.
+type jwtHeader struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+}
+
+// jwtClaims are the claims of a JWT the verifier looks at. Scopes are read from the
+// space separated scope claim and the scp list, as issued by common gateways.
+type jwtClaims struct {
+	Subject   string   `json:"sub"`
+	Audience  audience `json:"aud"`
+	ExpiresAt *float64 `json:"exp"`
+	NotBefore *float64 `json:"nbf"`
+	Scope     string   `json:"scope"`
+	Scp       []string `json:"scp"`
+}
+
+// audience is the aud claim, a single string or a list of them.
+type audience []string
+
+// UnmarshalJSON accepts both forms of the aud claim.
+func (a *audience) UnmarshalJSON(data []byte) error {
+	var single string
+	if err := json.Unmarshal(data, &single); err == nil {
+		*a = audience{single}
+		return nil
+	}
+	var list []string
+	if err := json.Unmarshal(data, &list); err != nil {
+		return errors.New("aud must be a string or a list of strings")
+	}
+	*a = list
+	return nil
+}
+
+// Verify checks the signature and the exp, nbf and aud claims of token, and returns the
+// client it was issued to. The Principal has the subject of the token and those of its
+// scopes the API knows, others being ignored.
+func (v *JWTVerifier) Verify(token string) (Principal, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return Principal{}, fmt.Errorf("%w: malformed token", ErrInvalidToken)
+	}
+
+	var header jwtHeader
+	if err := decodeSegment(parts[0], &header); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed header", ErrInvalidToken)
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed signature", ErrInvalidToken)
+	}
+	if err := v.verifySignature(header, parts[0]+"."+parts[1], signature); err != nil {
+		return Principal{}, err
+	}
+
+	var claims jwtClaims
+	if err := decodeSegment(parts[1], &claims); err != nil {
+		return Principal{}, fmt.Errorf("%w: malformed claims", ErrInvalidToken)
+	}
+	if err := v.checkClaims(claims); err != nil {
+		return Principal{}, err
+	}
+
+	return Principal{Subject: claims.Subject, Scopes: claims.scopes()}, nil
+}
+
+// verifySignature checks signature against the keys for the algorithm named in header, and
+// the one it names by ID if any. The algorithm must match the type of the key, so that a
+// public key is never used as an HMAC secret.
+func (v *JWTVerifier) verifySignature(header jwtHeader, signed string, signature []byte) error {
+	switch header.Alg {
+	case AlgHS256, AlgRS256, AlgEdDSA:
+	default:
+		return fmt.Errorf("%w: unsupported algorithm %q", ErrInvalidToken, header.Alg)
+	}
+
+	for _, key := range v.keys {
+		if key.alg != header.Alg || (header.Kid != "" && key.id != "" && key.id != header.Kid) {
+			continue
+		}
+		if key.verify([]byte(signed), signature) {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: signature does not match any key", ErrInvalidToken)
+}
+
+// checkClaims checks that the token has a subject, is valid now and was issued for the
+// audience of v.
+func (v *JWTVerifier) checkClaims(claims jwtClaims) error {
+	now := v.now()
+	if claims.Subject == "" {
+		return fmt.Errorf("%w: missing sub claim", ErrInvalidToken)
+	}
+	if claims.ExpiresAt == nil {
+		return fmt.Errorf("%w: missing exp claim", ErrInvalidToken)
+	}
+	if now.After(numericDate(*claims.ExpiresAt).Add(clockSkew)) {
+		return fmt.Errorf("%w: token has expired", ErrInvalidToken)
+	}
+	if claims.NotBefore != nil && now.Before(numericDate(*claims.NotBefore).Add(-clockSkew)) {
+		return fmt.Errorf("%w: token is not valid yet", ErrInvalidToken)
+	}
+	for _, aud := range claims.Audience {
+		if aud == v.audience {
+			return nil
+		}
+	}
+	return fmt.Errorf("%w: token was not issued for %s", ErrInvalidToken, v.audience)
+}
+
+// scopes returns the scopes of the claims the API knows.
+func (c jwtClaims) scopes() []Scope {
+	names := append(strings.Fields(c.Scope), c.Scp...)
+	scopes := []Scope{}
+	for _, name := range names {
+		if s, err := ParseScopes([]string{name}); err == nil {
+			scopes = append(scopes, s[0])
+		}
+	}
+	return scopes
+}
+
+// verify reports whether signature is the signature of signed by k.
+func (k JWTKey) verify(signed, signature []byte) bool {
+	switch key := k.key.(type) {
+	case []byte:
+		mac := hmac.New(sha256.New, key)
+		mac.Write(signed)
+		return hmac.Equal(mac.Sum(nil), signature)
+	case *rsa.PublicKey:
+		sum := sha256.Sum256(signed)
+		return rsa.VerifyPKCS1v15(key, crypto.SHA256, sum[:], signature) == nil
+	case ed25519.PublicKey:
+		return ed25519.Verify(key, signed, signature)
+	}
+	return false
+}
+
+// decodeSegment decodes a base64url encoded JSON segment of a JWT into v.
+func decodeSegment(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
+
+// numericDate converts a JWT NumericDate, in seconds since the epoch, to a time.
+func numericDate(seconds float64) time.Time {
+	whole, frac := math.Modf(seconds)
+	return time.Unix(int64(whole), int64(frac*1e9))
+}
@@ -0,0 +1,236 @@
+package auth
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//go:generate mockgen -source=keys.go -destination=../../internal/handler/mocks/mock_keys.go -package=mocks
+
+// Key prefixes of the records kept for API keys. A key record is found from the key
+// itself through the index of key hashes.
+const (
+	keyPrefix     = "apikey_"
+	keyHashPrefix = "apikeyhash_"
+)
+
+// tokenPrefix starts every API key generated by Create, so that leaked keys are easy to
+// recognise.
+const tokenPrefix = "pko_"
+
+var (
+	// ErrInvalidKey is returned by Authenticate for an unknown or revoked key.
+	ErrInvalidKey = errors.New("invalid API key")
+	// ErrKeyNotFound is returned when no API key has the requested ID.
+	ErrKeyNotFound = errors.New("API key not found")
+)
+
+// KeyStoreInterface manages the API keys clients authenticate with.
+type KeyStoreInterface interface {
+	Create(name string, scopes []Scope) (APIKey, string, error)
+	List() ([]APIKey, error)
+	Revoke(id string) error
+	Authenticate(token string) (APIKey, error)
+}
+
+// APIKey describes an API key. The key itself is only known to its holder; the store
+// keeps its SHA-256 hash.
+type APIKey struct {
+	ID        string     `json:"id"`
+	Name      string     `json:"name"`
+	Scopes    []Scope    `json:"scopes"`
+	CreatedAt time.Time  `json:"created_at"`
+	RevokedAt *time.Time `json:"revoked_at,omitempty"`
+}
+
+// keyRecord is an APIKey as stored, with the hash of the key.
+type keyRecord struct {
+	APIKey
+	Hash string `json:"hash"`
+}
+
+// KeyStore keeps API keys in a LevelDB database shared with other records. mu serialises
+// writes so that revoking reads and modifies a record atomically.
+type KeyStore struct {
+	db *leveldb.DB
+	mu sync.Mutex
+}
+
+// NewKeyStore returns a KeyStore keeping its records in db.
+func NewKeyStore(db *leveldb.DB) *KeyStore {
+	return &KeyStore{db: db}
+}
+
+// Create stores a new API key named name and granted scopes, and returns it together with
+// the key itself, which cannot be recovered later.
+func (ks *KeyStore) Create(name string, scopes []Scope) (APIKey, string, error) {
+	secret, err := randomString(32)
+	if err != nil {
+		return APIKey{}, "", err
+	}
+	token := tokenPrefix + secret
+	key, err := ks.add(name, scopes, token)
+	return key, token, err
+}
+
+// Ensure stores token as an API key named name and granted scopes, unless it was stored
+// before, even if it has been revoked since. It lets an operator configure the key used
+// to create all others.
+func (ks *KeyStore) Ensure(token, name string, scopes []Scope) error {
+	if token == "" {
+		return ErrInvalidKey
+	}
+	stored, err := ks.db.Has([]byte(keyHashPrefix+hashToken(token)), nil)
+	if err != nil || stored {
+		return err
+	}
+	_, err = ks.add(name, scopes, token)
+	return err
+}
+
+// add stores token as a new API key.
+func (ks *KeyStore) add(name string, scopes []Scope, token string) (APIKey, error) {
+	id, err := randomID()
+	if err != nil {
+		return APIKey{}, err
+	}
+	record := keyRecord{
+		APIKey: APIKey{
+			ID:        id,
+			Name:      name,
+			Scopes:    scopes,
+			CreatedAt: time.Now().UTC(),
+		},
+		Hash: hashToken(token),
+	}
+	value, err := json.Marshal(record)
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	batch := new(leveldb.Batch)
+	batch.Put([]byte(keyPrefix+id), value)
+	batch.Put([]byte(keyHashPrefix+record.Hash), []byte(id))
+
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+	if err := ks.db.Write(batch, nil); err != nil {
+		return APIKey{}, err
+	}
+	return record.APIKey, nil
+}
+
+// List returns every API key, revoked ones included, oldest first.
+func (ks *KeyStore) List() ([]APIKey, error) {
+	iter := ks.db.NewIterator(util.BytesPrefix([]byte(keyPrefix)), nil)
+	defer iter.Release()
+
+	keys := []APIKey{}
+	for iter.Next() {
+		var record keyRecord
+		if err := json.Unmarshal(iter.Value(), &record); err != nil {
+			return nil, fmt.Errorf("invalid record for %s: %v", iter.Key(), err)
+		}
+		keys = append(keys, record.APIKey)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	sort.SliceStable(keys, func(i, j int) bool { return keys[i].CreatedAt.Before(keys[j].CreatedAt) })
+	return keys, nil
+}
+
+// Revoke stops the API key id from authenticating. Its record is kept, marked with the
+// time it was revoked, so that the key is never accepted again.
+func (ks *KeyStore) Revoke(id string) error {
+	ks.mu.Lock()
+	defer ks.mu.Unlock()
+
+	record, err := ks.get(id)
+	if err != nil {
+		return err
+	}
+	if record.RevokedAt != nil {
+		return nil
+	}
+	now := time.Now().UTC()
+	record.RevokedAt = &now
+
+	value, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return ks.db.Put([]byte(keyPrefix+id), value, nil)
+}
+
+// Authenticate returns the API key token is, or ErrInvalidKey when it is unknown or
+// revoked.
+func (ks *KeyStore) Authenticate(token string) (APIKey, error) {
+	id, err := ks.db.Get([]byte(keyHashPrefix+hashToken(token)), nil)
+	if err == leveldb.ErrNotFound {
+		return APIKey{}, ErrInvalidKey
+	}
+	if err != nil {
+		return APIKey{}, err
+	}
+
+	record, err := ks.get(string(id))
+	if err == ErrKeyNotFound || (err == nil && record.RevokedAt != nil) {
+		return APIKey{}, ErrInvalidKey
+	}
+	return record.APIKey, err
+}
+
+// get returns the record of the API key id.
+func (ks *KeyStore) get(id string) (keyRecord, error) {
+	value, err := ks.db.Get([]byte(keyPrefix+id), nil)
+	if err == leveldb.ErrNotFound {
+		return keyRecord{}, ErrKeyNotFound
+	}
+	if err != nil {
+		return keyRecord{}, err
+	}
+
+	var record keyRecord
+	if err := json.Unmarshal(value, &record); err != nil {
+		return keyRecord{}, fmt.Errorf("invalid record for API key %s: %v", id, err)
+	}
+	return record, nil
+}
+
+// hashToken returns the hex encoded SHA-256 hash of token. API keys are long random
+// strings, so a fast hash is enough to keep them from being read off the database.
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// randomID returns a random API key ID.
+func randomID() (string, error) {
+	b := make([]byte, 8)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// randomString returns n random bytes, URL safe base64 encoded.
+func randomString(n int) (string, error) {
+	b := make([]byte, n)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
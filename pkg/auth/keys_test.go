@@ -0,0 +1,84 @@
+package auth_test
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func newKeyStore(t *testing.T) *auth.KeyStore {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+	return auth.NewKeyStore(db)
+}
+
+func TestKeyStore_CreateAuthenticate(t *testing.T) {
+	ks := newKeyStore(t)
+
+	key, token, err := ks.Create("erp", []auth.Scope{auth.ScopePacksRead})
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(token, "pko_"))
+	assert.Equal(t, "erp", key.Name)
+	assert.NotEmpty(t, key.ID)
+
+	found, err := ks.Authenticate(token)
+	assert.NoError(t, err)
+	assert.Equal(t, key.ID, found.ID)
+	assert.Equal(t, []auth.Scope{auth.ScopePacksRead}, found.Scopes)
+
+	_, err = ks.Authenticate(token + "x")
+	assert.ErrorIs(t, err, auth.ErrInvalidKey)
+}
+
+func TestKeyStore_ListRevoke(t *testing.T) {
+	ks := newKeyStore(t)
+
+	first, token, err := ks.Create("first", []auth.Scope{auth.ScopeAdmin})
+	require.NoError(t, err)
+	second, _, err := ks.Create("second", []auth.Scope{auth.ScopeOrderCalculate})
+	require.NoError(t, err)
+
+	keys, err := ks.List()
+	assert.NoError(t, err)
+	require.Len(t, keys, 2)
+	assert.Equal(t, first.ID, keys[0].ID)
+	assert.Equal(t, second.ID, keys[1].ID)
+
+	assert.NoError(t, ks.Revoke(first.ID))
+	_, err = ks.Authenticate(token)
+	assert.ErrorIs(t, err, auth.ErrInvalidKey)
+
+	keys, err = ks.List()
+	assert.NoError(t, err)
+	assert.NotNil(t, keys[0].RevokedAt)
+	assert.Nil(t, keys[1].RevokedAt)
+
+	assert.ErrorIs(t, ks.Revoke("missing"), auth.ErrKeyNotFound)
+}
+
+func TestKeyStore_Ensure(t *testing.T) {
+	ks := newKeyStore(t)
+
+	require.NoError(t, ks.Ensure("bootstrap-secret", "bootstrap", []auth.Scope{auth.ScopeAdmin}))
+	require.NoError(t, ks.Ensure("bootstrap-secret", "bootstrap", []auth.Scope{auth.ScopeAdmin}))
+
+	keys, err := ks.List()
+	assert.NoError(t, err)
+	require.Len(t, keys, 1)
+
+	key, err := ks.Authenticate("bootstrap-secret")
+	assert.NoError(t, err)
+
+	// A revoked key stays revoked.
+	require.NoError(t, ks.Revoke(key.ID))
+	require.NoError(t, ks.Ensure("bootstrap-secret", "bootstrap", []auth.Scope{auth.ScopeAdmin}))
+	_, err = ks.Authenticate("bootstrap-secret")
+	assert.ErrorIs(t, err, auth.ErrInvalidKey)
+
+	assert.Error(t, ks.Ensure("", "bootstrap", []auth.Scope{auth.ScopeAdmin}))
+}
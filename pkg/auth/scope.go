@@ -0,0 +1,69 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+)
+
+// Scope is a permission granted to an API client.
+type Scope string
+
+// Scopes understood by the API. ScopeAdmin grants every other scope as well.
+const (
+	ScopeOrderCalculate Scope = "order:calculate"
+	ScopePacksRead      Scope = "packs:read"
+	ScopePacksWrite     Scope = "packs:write"
+	ScopeAdmin          Scope = "admin"
+)
+
+// ErrInvalidScope is returned for a scope that is not one of the known ones.
+var ErrInvalidScope = errors.New("invalid scope")
+
+// ParseScopes checks that names holds at least one scope, all of them known.
+func ParseScopes(names []string) ([]Scope, error) {
+	if len(names) == 0 {
+		return nil, fmt.Errorf("%w: at least one scope is required", ErrInvalidScope)
+	}
+	scopes := make([]Scope, len(names))
+	for i, name := range names {
+		switch s := Scope(name); s {
+		case ScopeOrderCalculate, ScopePacksRead, ScopePacksWrite, ScopeAdmin:
+			scopes[i] = s
+		default:
+			return nil, fmt.Errorf("%w %q", ErrInvalidScope, name)
+		}
+	}
+	return scopes, nil
+}
+
+// Principal is the authenticated client behind a request: the subject it is known by and
+// the scopes it was granted.
+type Principal struct {
+	Subject string
+	Scopes  []Scope
+}
+
+// Has reports whether p was granted scope, directly or through ScopeAdmin.
+func (p Principal) Has(scope Scope) bool {
+	for _, s := range p.Scopes {
+		if s == scope || s == ScopeAdmin {
+			return true
+		}
+	}
+	return false
+}
+
+// principalKey is the context key of the Principal of a request.
+type principalKey struct{}
+
+// WithPrincipal returns a copy of ctx carrying p.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the Principal carried by ctx, if any.
+func PrincipalFrom(ctx context.Context) (Principal, bool) {
+	p, ok := ctx.Value(principalKey{}).(Principal)
+	return p, ok
+}
@@ -0,0 +1,35 @@
+package auth_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/auth"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseScopes(t *testing.T) {
+	scopes, err := auth.ParseScopes([]string{"packs:read", "order:calculate"})
+	assert.NoError(t, err)
+	assert.Equal(t, []auth.Scope{auth.ScopePacksRead, auth.ScopeOrderCalculate}, scopes)
+
+	_, err = auth.ParseScopes(nil)
+	assert.ErrorIs(t, err, auth.ErrInvalidScope)
+	_, err = auth.ParseScopes([]string{"packs:read", "root"})
+	assert.ErrorIs(t, err, auth.ErrInvalidScope)
+}
+
+func TestPrincipal(t *testing.T) {
+	reader := auth.Principal{Subject: "reader", Scopes: []auth.Scope{auth.ScopePacksRead}}
+	assert.True(t, reader.Has(auth.ScopePacksRead))
+	assert.False(t, reader.Has(auth.ScopePacksWrite))
+
+	admin := auth.Principal{Subject: "admin", Scopes: []auth.Scope{auth.ScopeAdmin}}
+	assert.True(t, admin.Has(auth.ScopePacksWrite))
+
+	_, ok := auth.PrincipalFrom(context.Background())
+	assert.False(t, ok)
+	p, ok := auth.PrincipalFrom(auth.WithPrincipal(context.Background(), reader))
+	assert.True(t, ok)
+	assert.Equal(t, reader, p)
+}
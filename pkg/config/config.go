@@ -3,16 +3,32 @@ package config
 import (
 	"context"
 	"os"
+	"strconv"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 )
 
 // Default Values used if no environment variables are set
 var (
-	dbPath      = "/tmp/packs.db"
-	serverPort  = ":8080"
-	loggerLevel = "DEBUG"
-	environment = "dev"
+	dbPath             = "/tmp/packs.db"
+	serverPort         = ":8080"
+	loggerLevel        = "DEBUG"
+	environment        = "dev"
+	cacheSize          = strconv.Itoa(optimizer.DefaultCacheSize)
+	cacheTTL           = "0s"
+	seed               = sizer.DefaultSeed
+	seedFile           = ""
+	authEnabled        = "false"
+	adminAPIKey        = ""
+	jwtKeysFile        = ""
+	jwtAudience        = ""
+	rateLimitCalculate = "300/1m"
+	rateLimitMutate    = "60/1m"
+	rateLimitPersist   = "false"
+	idempotencyTTL     = "24h"
 )
 
 // Config holds application configuration values
@@ -21,6 +37,30 @@ type Config struct {
 	Env        string
 	DbPath     string
 	Logger     logger.Logger
+	CacheSize  int
+	CacheTTL   time.Duration
+	// Seed lists the pack sizes an empty default catalog starts with, or is "none" to
+	// start it empty. SeedFile, when set, names a JSON, CSV or YAML file of packs used
+	// instead.
+	Seed     string
+	SeedFile string
+	// AuthEnabled requires an API key on every route but /health. AdminAPIKey, when set,
+	// is stored as an admin key on start so that the first keys can be created with it.
+	AuthEnabled bool
+	AdminAPIKey string
+	// JWTKeysFile, when set with AuthEnabled, names a JWKS document, PEM public keys or an
+	// HMAC secret bearer tokens are verified with. JWTAudience is the aud they must hold.
+	JWTKeysFile string
+	JWTAudience string
+	// RateLimitCalculate and RateLimitMutate are the requests per period, such as
+	// "300/1m", each client may make to calculate orders and to change pack sizes or API
+	// keys, or "none". RateLimitPersist keeps the budgets in LevelDB across restarts.
+	RateLimitCalculate string
+	RateLimitMutate    string
+	RateLimitPersist   bool
+	// IdempotencyTTL is how long the response to a request with an Idempotency-Key is
+	// replayed for repeats. Zero ignores the header.
+	IdempotencyTTL time.Duration
 }
 
 // New creates a new Config instance with provided values
@@ -56,6 +96,20 @@ func GetDefaultConfig() *Config {
 
 	ctx := context.Background()
 	config := New(ctx, serverPort, environment, dbPath, log)
+	// The cache, the seed, the auth, the rate limits and the idempotency TTL are read into
+	// the config only, so that the defaults above stay the defaults for every call.
+	config.CacheSize = parseInt(getEnv("CACHE_SIZE", cacheSize), optimizer.DefaultCacheSize)
+	config.CacheTTL = parseDuration(getEnv("CACHE_TTL", cacheTTL), optimizer.DefaultCacheTTL)
+	config.Seed = getEnv("SEED", seed)
+	config.SeedFile = getEnv("SEED_FILE", seedFile)
+	config.AuthEnabled = parseBool(getEnv("AUTH_ENABLED", authEnabled), false)
+	config.AdminAPIKey = getEnv("ADMIN_API_KEY", adminAPIKey)
+	config.JWTKeysFile = getEnv("JWT_KEYS_FILE", jwtKeysFile)
+	config.JWTAudience = getEnv("JWT_AUDIENCE", jwtAudience)
+	config.RateLimitCalculate = getEnv("RATE_LIMIT_CALCULATE", rateLimitCalculate)
+	config.RateLimitMutate = getEnv("RATE_LIMIT_MUTATE", rateLimitMutate)
+	config.RateLimitPersist = parseBool(getEnv("RATE_LIMIT_PERSIST", rateLimitPersist), false)
+	config.IdempotencyTTL = parseDuration(getEnv("IDEMPOTENCY_TTL", idempotencyTTL), 24*time.Hour)
 
 	return config
 }
@@ -68,3 +122,32 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+// parseInt converts v to an int, or returns the fallback if v is not a valid integer.
+func parseInt(v string, fallback int) int {
+	n, err := strconv.Atoi(v)
+	if err != nil {
+		return fallback
+	}
+	return n
+}
+
+// parseBool converts v to a bool, or returns the fallback if v is not a valid boolean
+// such as "true" or "0".
+func parseBool(v string, fallback bool) bool {
+	b, err := strconv.ParseBool(v)
+	if err != nil {
+		return fallback
+	}
+	return b
+}
+
+// parseDuration converts v to a time.Duration, or returns the fallback if v is not
+// a valid duration such as "30s" or "5m".
+func parseDuration(v string, fallback time.Duration) time.Duration {
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		return fallback
+	}
+	return d
+}
@@ -3,8 +3,11 @@ package config
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 	"github.com/stretchr/testify/require"
 	"go.uber.org/zap"
 )
@@ -28,3 +31,93 @@ func TestGetEnv(t *testing.T) {
 	v := getEnv("a", "b")
 	require.Equal(t, "b", v)
 }
+
+func TestGetDefaultConfig_Cache(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("CACHE_SIZE", "10")
+		t.Setenv("CACHE_TTL", "5m")
+
+		config := GetDefaultConfig()
+		require.Equal(t, 10, config.CacheSize)
+		require.Equal(t, 5*time.Minute, config.CacheTTL)
+	})
+
+	config := GetDefaultConfig()
+	require.Equal(t, optimizer.DefaultCacheSize, config.CacheSize)
+	require.Equal(t, optimizer.DefaultCacheTTL, config.CacheTTL)
+}
+
+func TestGetDefaultConfig_Seed(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("SEED", "none")
+		t.Setenv("SEED_FILE", "seed.csv")
+
+		config := GetDefaultConfig()
+		require.Equal(t, "none", config.Seed)
+		require.Equal(t, "seed.csv", config.SeedFile)
+	})
+
+	config := GetDefaultConfig()
+	require.Equal(t, sizer.DefaultSeed, config.Seed)
+	require.Empty(t, config.SeedFile)
+}
+
+func TestGetDefaultConfig_Auth(t *testing.T) {
+	t.Run("env", func(t *testing.T) {
+		t.Setenv("AUTH_ENABLED", "true")
+		t.Setenv("ADMIN_API_KEY", "secret")
+		t.Setenv("JWT_KEYS_FILE", "/etc/packs/jwks.json")
+		t.Setenv("JWT_AUDIENCE", "packs")
+
+		config := GetDefaultConfig()
+		require.True(t, config.AuthEnabled)
+		require.Equal(t, "secret", config.AdminAPIKey)
+		require.Equal(t, "/etc/packs/jwks.json", config.JWTKeysFile)
+		require.Equal(t, "packs", config.JWTAudience)
+	})
+
+	config := GetDefaultConfig()
+	require.False(t, config.AuthEnabled)
+	require.Empty(t, config.AdminAPIKey)
+	require.Empty(t, config.JWTKeysFile)
+	require.Empty(t, config.JWTAudience)
+}
+
+func TestGetDefaultConfig_RateLimit(t *testing.T) {
+	config := GetDefaultConfig()
+	require.Equal(t, "300/1m", config.RateLimitCalculate)
+	require.Equal(t, "60/1m", config.RateLimitMutate)
+	require.False(t, config.RateLimitPersist)
+
+	t.Setenv("RATE_LIMIT_CALCULATE", "10/1s")
+	t.Setenv("RATE_LIMIT_MUTATE", "none")
+	t.Setenv("RATE_LIMIT_PERSIST", "true")
+
+	config = GetDefaultConfig()
+	require.Equal(t, "10/1s", config.RateLimitCalculate)
+	require.Equal(t, "none", config.RateLimitMutate)
+	require.True(t, config.RateLimitPersist)
+}
+
+func TestGetDefaultConfig_IdempotencyTTL(t *testing.T) {
+	require.Equal(t, 24*time.Hour, GetDefaultConfig().IdempotencyTTL)
+
+	t.Setenv("IDEMPOTENCY_TTL", "1h")
+	require.Equal(t, time.Hour, GetDefaultConfig().IdempotencyTTL)
+}
+
+func TestParseBool(t *testing.T) {
+	require.True(t, parseBool("1", false))
+	require.False(t, parseBool("false", true))
+	require.True(t, parseBool("x", true))
+}
+
+func TestParseInt(t *testing.T) {
+	require.Equal(t, 42, parseInt("42", 1))
+	require.Equal(t, 1, parseInt("x", 1))
+}
+
+func TestParseDuration(t *testing.T) {
+	require.Equal(t, time.Second, parseDuration("1s", 0))
+	require.Equal(t, time.Minute, parseDuration("x", time.Minute))
+}
@@ -0,0 +1,195 @@
+package idempotency
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//go:generate mockgen -source=store.go -destination=../../internal/handler/mocks/mock_idempotency.go -package=mocks
+
+// recordPrefix starts the keys of the responses a Store keeps.
+const recordPrefix = "idempotency_"
+
+// sweepEvery is how many calls to Begin go between two sweeps of the expired responses.
+const sweepEvery = 1024
+
+var (
+	// ErrKeyReused is returned by Begin for a key first used for another request.
+	ErrKeyReused = errors.New("idempotency key was used for another request")
+	// ErrInProgress is returned by Begin for a key whose first request is still being
+	// served.
+	ErrInProgress = errors.New("a request with this idempotency key is in progress")
+)
+
+// StoreInterface keeps the first response to each idempotency key so that repeated
+// requests are answered without being served again.
+type StoreInterface interface {
+	Begin(key, fingerprint string) (*Response, error)
+	Complete(key, fingerprint string, res Response) error
+	Abandon(key string)
+}
+
+// Response is a response as stored and replayed.
+type Response struct {
+	Status      int    `json:"status"`
+	ContentType string `json:"content_type,omitempty"`
+	Body        []byte `json:"body,omitempty"`
+}
+
+// record is a Response as stored, with the fingerprint of its request and the time it
+// expires.
+type record struct {
+	Response
+	Fingerprint string    `json:"fingerprint"`
+	ExpiresAt   time.Time `json:"expires_at"`
+}
+
+// Store keeps responses in a LevelDB database shared with other records, for ttl. Keys
+// whose first request is being served are only held in memory, so that a crash never
+// leaves one locked.
+type Store struct {
+	db  *leveldb.DB
+	ttl time.Duration
+	now func() time.Time
+
+	mu      sync.Mutex
+	pending map[string]string
+	calls   int
+}
+
+// NewStore returns a Store keeping responses in db for ttl.
+func NewStore(db *leveldb.DB, ttl time.Duration) *Store {
+	return &Store{
+		db:      db,
+		ttl:     ttl,
+		now:     time.Now,
+		pending: make(map[string]string),
+	}
+}
+
+// Begin starts serving the request fingerprint identified by key. It returns the stored
+// response when key was already used for the same request, which should be replayed.
+// Otherwise it returns nil and holds key until Complete or Abandon is called. It returns
+// ErrKeyReused when key was used for another request, and ErrInProgress when the first
+// request with key is still being served.
+func (s *Store) Begin(key, fingerprint string) (*Response, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.calls++; s.calls%sweepEvery == 0 {
+		if err := s.sweep(); err != nil {
+			return nil, err
+		}
+	}
+
+	if pending, ok := s.pending[key]; ok {
+		if pending != fingerprint {
+			return nil, ErrKeyReused
+		}
+		return nil, ErrInProgress
+	}
+
+	rec, err := s.get(key)
+	if err != nil {
+		return nil, err
+	}
+	if rec != nil {
+		if rec.Fingerprint != fingerprint {
+			return nil, ErrKeyReused
+		}
+		return &rec.Response, nil
+	}
+
+	s.pending[key] = fingerprint
+	return nil, nil
+}
+
+// Complete stores res as the response to the request fingerprint identified by key, and
+// releases key.
+func (s *Store) Complete(key, fingerprint string, res Response) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, key)
+
+	value, err := json.Marshal(record{
+		Response:    res,
+		Fingerprint: fingerprint,
+		ExpiresAt:   s.now().Add(s.ttl),
+	})
+	if err != nil {
+		return err
+	}
+	return s.db.Put([]byte(recordKey(key)), value, nil)
+}
+
+// Abandon releases key without storing a response, so that the request may be served
+// again.
+func (s *Store) Abandon(key string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	delete(s.pending, key)
+}
+
+// get returns the unexpired record of key, or nil if there is none.
+func (s *Store) get(key string) (*record, error) {
+	value, err := s.db.Get([]byte(recordKey(key)), nil)
+	if err == leveldb.ErrNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var rec record
+	if err := json.Unmarshal(value, &rec); err != nil {
+		return nil, fmt.Errorf("invalid record for idempotency key: %v", err)
+	}
+	if !s.now().Before(rec.ExpiresAt) {
+		return nil, nil
+	}
+	return &rec, nil
+}
+
+// sweep deletes the expired records.
+func (s *Store) sweep() error {
+	now := s.now()
+	batch := new(leveldb.Batch)
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(recordPrefix)), nil)
+	for iter.Next() {
+		var rec record
+		if err := json.Unmarshal(iter.Value(), &rec); err != nil || !now.Before(rec.ExpiresAt) {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+// recordKey returns the database key of the record of key. Keys are hashed since clients
+// choose them.
+func recordKey(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return recordPrefix + hex.EncodeToString(sum[:])
+}
+
+// Fingerprint returns a digest of the parts of a request that must match for a repeat to
+// be replayed.
+func Fingerprint(parts ...[]byte) string {
+	h := sha256.New()
+	for _, part := range parts {
+		fmt.Fprintf(h, "%d:", len(part))
+		h.Write(part)
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
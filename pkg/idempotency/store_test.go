@@ -0,0 +1,84 @@
+package idempotency
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+func newStore(t *testing.T, ttl time.Duration) (*Store, func(time.Duration)) {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	require.NoError(t, err)
+	t.Cleanup(func() { db.Close() })
+
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	s := NewStore(db, ttl)
+	s.now = func() time.Time { return now }
+	return s, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestStore_Replay(t *testing.T) {
+	s, advance := newStore(t, time.Hour)
+	res := Response{Status: 201, ContentType: "application/json", Body: []byte(`{"status":"success"}`)}
+
+	stored, err := s.Begin("k1", "a")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+
+	// Repeats are refused while the first request is served.
+	_, err = s.Begin("k1", "a")
+	assert.ErrorIs(t, err, ErrInProgress)
+	_, err = s.Begin("k1", "b")
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	require.NoError(t, s.Complete("k1", "a", res))
+
+	stored, err = s.Begin("k1", "a")
+	require.NoError(t, err)
+	assert.Equal(t, &res, stored)
+	_, err = s.Begin("k1", "b")
+	assert.ErrorIs(t, err, ErrKeyReused)
+
+	// Once expired, the key can be used again.
+	advance(time.Hour)
+	stored, err = s.Begin("k1", "b")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestStore_Abandon(t *testing.T) {
+	s, _ := newStore(t, time.Hour)
+
+	_, err := s.Begin("k1", "a")
+	require.NoError(t, err)
+	s.Abandon("k1")
+
+	stored, err := s.Begin("k1", "b")
+	require.NoError(t, err)
+	assert.Nil(t, stored)
+}
+
+func TestStore_Sweep(t *testing.T) {
+	s, advance := newStore(t, time.Hour)
+
+	require.NoError(t, s.Complete("old", "a", Response{Status: 204}))
+	advance(30 * time.Minute)
+	require.NoError(t, s.Complete("new", "a", Response{Status: 204}))
+	advance(45 * time.Minute)
+	require.NoError(t, s.sweep())
+
+	has, err := s.db.Has([]byte(recordKey("old")), nil)
+	require.NoError(t, err)
+	assert.False(t, has)
+	has, err = s.db.Has([]byte(recordKey("new")), nil)
+	require.NoError(t, err)
+	assert.True(t, has)
+}
+
+func TestFingerprint(t *testing.T) {
+	assert.Equal(t, Fingerprint([]byte("POST"), []byte("/v1/order")), Fingerprint([]byte("POST"), []byte("/v1/order")))
+	assert.NotEqual(t, Fingerprint([]byte("ab"), []byte("c")), Fingerprint([]byte("a"), []byte("bc")))
+}
@@ -0,0 +1,233 @@
+package optimizer
+
+import "sort"
+
+// MaxAlternatives is the largest number of ranked combinations Calculate will list.
+const MaxAlternatives = 10
+
+// maxAlternativeCells bounds the memory of the alternatives table, counted in pack
+// counts stored: one per size, per kept combination, per total.
+const maxAlternativeCells = 1 << 24
+
+// ranked is a combination kept by the alternatives table, as a count per size.
+type ranked struct {
+	total  int
+	counts []int32
+	packs  int32
+	cost   float64
+}
+
+// candidate describes the combination for a cost function.
+func (e ranked) candidate() Candidate {
+	return Candidate{TotalItems: e.total, TotalPacks: int(e.packs), TotalCost: e.cost}
+}
+
+// kbest holds, for every exact total, up to k distinct combinations reaching it, best
+// first. Entry j of total t lives at index t*k+j, and its count of the i-th size at
+// (t*k+j)*n+i. picks and scratch are reused by merge.
+type kbest struct {
+	k, n    int
+	counts  []int32
+	packs   []int32
+	costs   []float64
+	lens    []uint8
+	picks   []pick
+	scratch []int32
+}
+
+// pick is a combination considered by merge: the entry at index from plus m packs of
+// the size being added.
+type pick struct {
+	c    Candidate
+	from int
+	m    int
+}
+
+// entry copies entry j of total out of the table.
+func (kb *kbest) entry(total, j int) ranked {
+	at := total*kb.k + j
+	return ranked{
+		total:  total,
+		counts: append([]int32(nil), kb.counts[at*kb.n:(at+1)*kb.n]...),
+		packs:  kb.packs[at],
+		cost:   kb.costs[at],
+	}
+}
+
+// alternatives returns up to k distinct combinations covering items, best first under
+// cf, shipping at most extra items beyond the order unless extra is unlimited. Only
+// combinations without a superfluous pack are listed: dropping their smallest pack
+// would leave the order short. It returns ErrAlternativesTooLarge when the table would
+// exceed maxAlternativeCells.
+//
+// Sizes are added one layer at a time from the largest down, keeping the k best
+// combinations per exact total. A combination reaching total without a superfluous
+// pack uses only sizes larger than total-items, so each total is collected right after
+// the last layer it may use.
+func alternatives(ps packSet, items, extra, k int, cf CostFunction) ([]*result, error) {
+	if len(ps.sizes) == 0 || items <= 0 || k <= 0 {
+		return nil, nil
+	}
+
+	largest := 0
+	for i, size := range ps.sizes {
+		if ps.stock(i) != 0 {
+			largest = size
+			break
+		}
+	}
+	if largest == 0 {
+		return nil, nil
+	}
+
+	n := len(ps.sizes)
+	limit := window(items, largest, extra)
+	if limit > maxAlternativeCells/(k*n) {
+		return nil, ErrAlternativesTooLarge
+	}
+
+	kb := &kbest{
+		k:       k,
+		n:       n,
+		counts:  make([]int32, limit*k*n),
+		packs:   make([]int32, limit*k),
+		costs:   make([]float64, limit*k),
+		lens:    make([]uint8, limit),
+		scratch: make([]int32, k*n),
+	}
+	kb.lens[0] = 1
+
+	var best []ranked
+	offer := func(e ranked) {
+		at := sort.Search(len(best), func(j int) bool {
+			return cf.Less(e.candidate(), best[j].candidate())
+		})
+		if at >= k {
+			return
+		}
+		best = append(best, ranked{})
+		copy(best[at+1:], best[at:])
+		best[at] = e
+		if len(best) > k {
+			best = best[:k]
+		}
+	}
+
+	for i, size := range ps.sizes {
+		if stock := ps.stock(i); stock != 0 {
+			if stock == unlimited || stock >= (limit-1)/size {
+				kb.addUnbounded(ps, i, limit, cf)
+			} else {
+				kb.addBounded(ps, i, stock, limit, cf)
+			}
+		}
+
+		next := 0
+		if i+1 < n {
+			next = ps.sizes[i+1]
+		}
+		hi := items + size
+		if hi > limit {
+			hi = limit
+		}
+		for total := items + next; total < hi; total++ {
+			for j := 0; j < int(kb.lens[total]); j++ {
+				offer(kb.entry(total, j))
+			}
+		}
+	}
+
+	results := make([]*result, len(best))
+	for r, e := range best {
+		res := &result{
+			packs:      make([]int, 0, e.packs),
+			totalItems: e.total,
+			totalCost:  e.cost,
+		}
+		for i, count := range e.counts {
+			for c := int32(0); c < count; c++ {
+				res.packs = append(res.packs, ps.sizes[i])
+			}
+		}
+		results[r] = res
+	}
+	return results, nil
+}
+
+// addUnbounded adds any number of packs of the i-th size. Walking totals upwards lets
+// an entry extend combinations that already hold this size; each combination is still
+// produced once, from the same combination with one pack fewer.
+func (kb *kbest) addUnbounded(ps packSet, i, limit int, cf CostFunction) {
+	size := ps.sizes[i]
+	for total := size; total < limit; total++ {
+		kb.merge(ps, i, total, 1, cf)
+	}
+}
+
+// addBounded adds up to stock packs of the i-th size. Walking totals downwards keeps
+// every source total below the current one untouched by this layer.
+func (kb *kbest) addBounded(ps packSet, i, stock, limit int, cf CostFunction) {
+	size := ps.sizes[i]
+	for total := limit - 1; total >= size; total-- {
+		max := stock
+		if total/size < max {
+			max = total / size
+		}
+		kb.merge(ps, i, total, max, cf)
+	}
+}
+
+// merge ranks the entries already at total together with those reached by adding 1 to
+// max packs of the i-th size to smaller totals, and keeps the best k. Only the winners
+// are copied, through a scratch area, since total may be one of its own sources.
+func (kb *kbest) merge(ps packSet, i, total, max int, cf CostFunction) {
+	size := ps.sizes[i]
+
+	picks := kb.picks[:0]
+	for m := 0; m <= max; m++ {
+		from := total - m*size
+		for j := 0; j < int(kb.lens[from]); j++ {
+			at := from*kb.k + j
+			p := pick{
+				c: Candidate{
+					TotalItems: total,
+					TotalPacks: int(kb.packs[at]) + m,
+					TotalCost:  kb.costs[at] + float64(m)*ps.price(i),
+				},
+				from: at,
+				m:    m,
+			}
+			// Insertion keeps picks ranked and stable without allocating.
+			pos := len(picks)
+			for pos > 0 && cf.Less(p.c, picks[pos-1].c) {
+				pos--
+			}
+			if pos >= kb.k {
+				continue
+			}
+			picks = append(picks, pick{})
+			copy(picks[pos+1:], picks[pos:])
+			picks[pos] = p
+			if len(picks) > kb.k {
+				picks = picks[:kb.k]
+			}
+		}
+	}
+	kb.picks = picks
+	if len(picks) == 0 {
+		return
+	}
+
+	n := kb.n
+	for e, p := range picks {
+		copy(kb.scratch[e*n:(e+1)*n], kb.counts[p.from*n:(p.from+1)*n])
+		kb.scratch[e*n+i] += int32(p.m)
+	}
+	for e, p := range picks {
+		at := total*kb.k + e
+		copy(kb.counts[at*n:(at+1)*n], kb.scratch[e*n:(e+1)*n])
+		kb.packs[at] = int32(p.c.TotalPacks)
+		kb.costs[at] = p.c.TotalCost
+	}
+	kb.lens[total] = uint8(len(picks))
+}
@@ -0,0 +1,124 @@
+package optimizer
+
+import (
+	"fmt"
+	"math/rand"
+	"sort"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+)
+
+// bruteForceAll enumerates every combination within stock and overfill that has no
+// superfluous pack and returns them ranked under cf.
+func bruteForceAll(ps packSet, items, extra int, cf CostFunction) []Candidate {
+	limits := make([]int, len(ps.sizes))
+	for i, size := range ps.sizes {
+		limits[i] = (items + ps.sizes[0]) / size
+		if stock := ps.stock(i); stock != unlimited && stock < limits[i] {
+			limits[i] = stock
+		}
+	}
+
+	var all []Candidate
+	counts := make([]int, len(ps.sizes))
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(ps.sizes) {
+			c := Candidate{}
+			smallest := 0
+			for j, n := range counts {
+				c.TotalItems += n * ps.sizes[j]
+				c.TotalPacks += n
+				c.TotalCost += float64(n) * ps.price(j)
+				if n > 0 {
+					smallest = ps.sizes[j]
+				}
+			}
+			if c.TotalItems < items || c.TotalItems-smallest >= items {
+				return
+			}
+			if extra != unlimited && c.TotalItems > items+extra {
+				return
+			}
+			all = append(all, c)
+			return
+		}
+		for n := 0; n <= limits[i]; n++ {
+			counts[i] = n
+			walk(i + 1)
+		}
+	}
+	walk(0)
+
+	sort.SliceStable(all, func(a, b int) bool { return cf.Less(all[a], all[b]) })
+	return all
+}
+
+func TestAlternatives_MatchBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(2))
+	costs := []CostFunction{FewestItems, FewestPacks, Weighted(7), LowestCost}
+
+	for run := 0; run < 150; run++ {
+		var packs []sizer.Pack
+		for _, size := range rng.Perm(30)[:1+rng.Intn(3)] {
+			pack := sizer.Pack{Size: size + 3, Price: float64(rng.Intn(20))}
+			if rng.Intn(3) == 0 {
+				pack.Stock = stockOf(rng.Intn(6))
+			}
+			packs = append(packs, pack)
+		}
+		ps := newPackSet(packs)
+		items := 1 + rng.Intn(100)
+		extra := []int{unlimited, 0, rng.Intn(10)}[rng.Intn(3)]
+		k := 1 + rng.Intn(6)
+
+		for _, cf := range costs {
+			t.Run(fmt.Sprintf("%d/%s", run, cf.Name()), func(t *testing.T) {
+				want := bruteForceAll(ps, items, extra, cf)
+				if len(want) > k {
+					want = want[:k]
+				}
+				got, err := alternatives(ps, items, extra, k, cf)
+				assert.NoError(t, err)
+				if !assert.Len(t, got, len(want)) {
+					return
+				}
+
+				seen := make(map[string]bool)
+				for r, res := range got {
+					c := Candidate{TotalItems: res.totalItems, TotalPacks: len(res.packs), TotalCost: res.totalCost}
+					assert.False(t, cf.Less(want[r], c), "rank %d: want %+v, got %+v", r, want[r], c)
+					assert.False(t, cf.Less(c, want[r]), "rank %d: want %+v, got %+v", r, want[r], c)
+
+					sum := 0
+					for _, size := range res.packs {
+						sum += size
+					}
+					assert.Equal(t, res.totalItems, sum)
+
+					key := fmt.Sprint(res.packs)
+					assert.False(t, seen[key], "duplicate combination %s", key)
+					seen[key] = true
+				}
+			})
+		}
+	}
+}
+
+func TestAlternatives_SkipsSuperfluousPacks(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 1000}})
+
+	got, err := alternatives(ps, 1, unlimited, 5, FewestPacks)
+	assert.NoError(t, err)
+	assert.Len(t, got, 1)
+	assert.Equal(t, []int{1000}, got[0].packs)
+}
+
+func TestAlternatives_TooLarge(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 1000}, {Size: 500}, {Size: 250}})
+
+	_, err := alternatives(ps, maxAlternativeCells, unlimited, MaxAlternatives, FewestItems)
+	assert.ErrorIs(t, err, ErrAlternativesTooLarge)
+}
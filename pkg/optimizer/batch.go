@@ -0,0 +1,90 @@
+package optimizer
+
+import "sync"
+
+// DefaultBatchWorkers is the number of lines CalculateBatch computes concurrently when no
+// positive worker count is given.
+const DefaultBatchWorkers = 8
+
+// OrderLine is one line of a batch order: a quantity of a single product.
+type OrderLine struct {
+	SKU          string `json:"sku"`
+	ItemsOrdered int    `json:"items_ordered"`
+}
+
+// LineResult is the outcome of one OrderLine. Exactly one of Result and Err is set.
+type LineResult struct {
+	SKU          string
+	ItemsOrdered int
+	Result       *OptimizationResult
+	Err          error
+}
+
+// BatchResult holds the results of a batch order, in the order of its lines, and the
+// totals of the lines that succeeded.
+type BatchResult struct {
+	Lines          []LineResult
+	ItemsRequested int
+	TotalItems     int
+	TotalPacks     int
+	TotalCost      float64
+	Failed         int
+}
+
+// Resolver returns the optimizer holding the pack sizes of a SKU.
+type Resolver func(sku string) (OptimizerInterface, error)
+
+// CalculateBatch calculates every line with the optimizer resolve returns for its SKU,
+// running at most workers lines at a time. A line that cannot be resolved or calculated
+// records its error and leaves the other lines untouched.
+func CalculateBatch(lines []OrderLine, resolve Resolver, workers int, options ...CalculateOption) *BatchResult {
+	if workers <= 0 {
+		workers = DefaultBatchWorkers
+	}
+	if workers > len(lines) {
+		workers = len(lines)
+	}
+
+	batch := &BatchResult{Lines: make([]LineResult, len(lines))}
+	indexes := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < workers; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range indexes {
+				batch.Lines[i] = calculateLine(lines[i], resolve, options)
+			}
+		}()
+	}
+	for i := range lines {
+		indexes <- i
+	}
+	close(indexes)
+	wg.Wait()
+
+	for _, line := range batch.Lines {
+		if line.Err != nil {
+			batch.Failed++
+			continue
+		}
+		batch.ItemsRequested += line.Result.ItemsRequested
+		batch.TotalItems += line.Result.TotalItems
+		batch.TotalPacks += line.Result.TotalPacks
+		batch.TotalCost += line.Result.TotalCost
+	}
+	return batch
+}
+
+// calculateLine resolves and calculates a single line.
+func calculateLine(line OrderLine, resolve Resolver, options []CalculateOption) LineResult {
+	res := LineResult{SKU: line.SKU, ItemsOrdered: line.ItemsOrdered}
+
+	opt, err := resolve(line.SKU)
+	if err != nil {
+		res.Err = err
+		return res
+	}
+	res.Result, res.Err = opt.Calculate(line.ItemsOrdered, options...)
+	return res
+}
@@ -0,0 +1,52 @@
+package optimizer_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestCalculateBatch(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	errUnknown := errors.New("unknown sku")
+	resolve := func(sku string) (optimizer.OptimizerInterface, error) {
+		if sku == "missing" {
+			return nil, errUnknown
+		}
+		return opt, nil
+	}
+
+	lines := []optimizer.OrderLine{
+		{SKU: "a", ItemsOrdered: 501},
+		{SKU: "missing", ItemsOrdered: 10},
+		{SKU: "b", ItemsOrdered: 0},
+		{SKU: "c", ItemsOrdered: 1000},
+	}
+	batch := optimizer.CalculateBatch(lines, resolve, 2)
+
+	if assert.Len(t, batch.Lines, 4) {
+		assert.Equal(t, "a", batch.Lines[0].SKU)
+		assert.Equal(t, []int{500, 250}, batch.Lines[0].Result.PacksUsed)
+		assert.ErrorIs(t, batch.Lines[1].Err, errUnknown)
+		assert.ErrorIs(t, batch.Lines[2].Err, optimizer.ErrInvalidQuantity)
+		assert.Equal(t, []int{1000}, batch.Lines[3].Result.PacksUsed)
+	}
+	assert.Equal(t, 2, batch.Failed)
+	assert.Equal(t, 1501, batch.ItemsRequested)
+	assert.Equal(t, 1750, batch.TotalItems)
+	assert.Equal(t, 3, batch.TotalPacks)
+}
+
+func TestCalculateBatch_Empty(t *testing.T) {
+	batch := optimizer.CalculateBatch(nil, nil, 0)
+	assert.Empty(t, batch.Lines)
+	assert.Equal(t, 0, batch.Failed)
+}
@@ -0,0 +1,116 @@
+package optimizer
+
+import "sort"
+
+// bundle is a group of packs of one size that the bounded solver takes or leaves whole.
+type bundle struct {
+	index int
+	count int
+}
+
+// solveBounded finds the combination that covers at least items and ranks best under
+// cf without using more packs of any size than are in stock, shipping at most extra
+// items beyond the order unless extra is unlimited. It returns nil when no combination
+// within stock satisfies those limits, and ErrQuantityTooLarge when the table or the
+// reconstruction bits would exceed maxSolveTotals entries.
+//
+// solve hands it the sizes below items only, trying larger ones as single packs, so a
+// huge size in stock does not widen the table. Each stock is split into bundles of 1,
+// 2, 4, ... packs plus a remainder, so that any count up to the stock is a sum of
+// distinct bundles, and a 0/1 knapsack over those bundles fills the same exact-total
+// table as solve. The same items+largest bound applies, tightened to the total stock
+// when every size is limited. Time is O(items*B) and memory O(items*B/64) for the
+// reconstruction bits, where B is the number of bundles, about len(sizes)*log2(stock).
+func solveBounded(ps packSet, items, extra int, cf CostFunction) (*result, error) {
+	largest := 0
+	for i, size := range ps.sizes {
+		if ps.stock(i) != 0 {
+			largest = size
+			break
+		}
+	}
+	if largest == 0 {
+		return nil, nil
+	}
+
+	limit := window(items, largest, extra)
+	counts := make([]int, len(ps.sizes))
+	capacity, limited := 0, true
+	for i, size := range ps.sizes {
+		counts[i] = (limit - 1) / size
+		if stock := ps.stock(i); stock == unlimited {
+			limited = false
+		} else if stock < counts[i] {
+			counts[i] = stock
+		}
+		capacity += counts[i] * size
+	}
+	if limited {
+		if capacity < items {
+			return nil, nil
+		}
+		if capacity < limit {
+			limit = capacity + 1
+		}
+	}
+
+	bundles := split(counts)
+	if limit > maxSolveTotals || len(bundles)*((limit+63)/64) > maxSolveTotals {
+		return nil, ErrQuantityTooLarge
+	}
+	tb := newTable(ps, limit)
+	taken := make([][]uint64, len(bundles))
+	for j, b := range bundles {
+		taken[j] = make([]uint64, (limit+63)/64)
+		weight := b.count * ps.sizes[b.index]
+		for total := limit - 1; total >= weight; total-- {
+			if !tb.reachable(total - weight) {
+				continue
+			}
+			c := tb.extend(ps, total-weight, b.index, b.count)
+			if !tb.reachable(total) || cf.Less(c, tb.candidate(total)) {
+				tb.set(c)
+				taken[j][total/64] |= 1 << (total % 64)
+			}
+		}
+	}
+
+	total := tb.pick(items, limit, cf)
+	if total < 0 {
+		return nil, nil
+	}
+
+	res := &result{
+		packs:      make([]int, 0, tb.packs[total]),
+		totalItems: total,
+		totalCost:  tb.candidate(total).TotalCost,
+	}
+	for j := len(bundles) - 1; j >= 0 && total > 0; j-- {
+		if taken[j][total/64]&(1<<(total%64)) == 0 {
+			continue
+		}
+		b := bundles[j]
+		for k := 0; k < b.count; k++ {
+			res.packs = append(res.packs, ps.sizes[b.index])
+		}
+		total -= b.count * ps.sizes[b.index]
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(res.packs)))
+	return res, nil
+}
+
+// split breaks each count into power-of-two bundles plus a remainder.
+func split(counts []int) []bundle {
+	var bundles []bundle
+	for i, count := range counts {
+		for n := 1; count > 0; n *= 2 {
+			if n > count {
+				n = count
+			}
+			bundles = append(bundles, bundle{index: i, count: n})
+			count -= n
+		}
+	}
+	return bundles
+}
@@ -0,0 +1,155 @@
+package optimizer
+
+import (
+	"container/list"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Default cache settings used when no option overrides them.
+const (
+	DefaultCacheSize = 1024
+	DefaultCacheTTL  = time.Duration(0)
+)
+
+// Stats reports the activity of an optimizer result cache.
+type Stats struct {
+	Hits      uint64 `json:"hits"`
+	Misses    uint64 `json:"misses"`
+	Evictions uint64 `json:"evictions"`
+	Entries   int    `json:"entries"`
+	Capacity  int    `json:"capacity"`
+}
+
+// cacheKey identifies a computed result by the size set it was solved against, the
+// requested quantity, the allowed overfill, the cost function that ranked it and the
+// number of alternatives listed, so results never leak between different size sets or
+// objectives.
+type cacheKey struct {
+	fingerprint  string
+	items        int
+	extra        int
+	cost         string
+	alternatives int
+}
+
+// cacheEntry is the value held by each element of the LRU list.
+type cacheEntry struct {
+	key     cacheKey
+	res     *result
+	expires time.Time
+}
+
+// resultCache is a concurrency-safe, bounded LRU store of solver results owned by one
+// Optimizer. Entries older than ttl are treated as misses; a zero ttl never expires.
+type resultCache struct {
+	mu       sync.Mutex
+	capacity int
+	ttl      time.Duration
+	order    *list.List
+	entries  map[cacheKey]*list.Element
+	stats    Stats
+	now      func() time.Time
+}
+
+// newResultCache creates an empty result cache holding at most capacity entries.
+// A non-positive capacity disables caching.
+func newResultCache(capacity int, ttl time.Duration) *resultCache {
+	return &resultCache{
+		capacity: capacity,
+		ttl:      ttl,
+		order:    list.New(),
+		entries:  make(map[cacheKey]*list.Element),
+		now:      time.Now,
+	}
+}
+
+// get returns the cached result for key, if present and not expired.
+func (c *resultCache) get(key cacheKey) (*result, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		c.stats.Misses++
+		return nil, false
+	}
+
+	entry := elem.Value.(*cacheEntry)
+	if c.ttl > 0 && c.now().After(entry.expires) {
+		c.remove(elem)
+		c.stats.Evictions++
+		c.stats.Misses++
+		return nil, false
+	}
+
+	c.order.MoveToFront(elem)
+	c.stats.Hits++
+	return entry.res, true
+}
+
+// put stores res under key, evicting the least recently used entries when full.
+func (c *resultCache) put(key cacheKey, res *result) {
+	if c.capacity <= 0 {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expires := c.now().Add(c.ttl)
+	if elem, ok := c.entries[key]; ok {
+		entry := elem.Value.(*cacheEntry)
+		entry.res = res
+		entry.expires = expires
+		c.order.MoveToFront(elem)
+		return
+	}
+
+	c.entries[key] = c.order.PushFront(&cacheEntry{key: key, res: res, expires: expires})
+	for c.order.Len() > c.capacity {
+		c.remove(c.order.Back())
+		c.stats.Evictions++
+	}
+}
+
+// reset drops every cached result while keeping the accumulated statistics.
+func (c *resultCache) reset() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.order.Init()
+	c.entries = make(map[cacheKey]*list.Element)
+}
+
+// snapshot returns a copy of the current statistics.
+func (c *resultCache) snapshot() Stats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	stats := c.stats
+	stats.Entries = c.order.Len()
+	stats.Capacity = c.capacity
+	return stats
+}
+
+// remove unlinks elem from both the list and the index. Callers must hold mu.
+func (c *resultCache) remove(elem *list.Element) {
+	c.order.Remove(elem)
+	delete(c.entries, elem.Value.(*cacheEntry).key)
+}
+
+// fingerprint returns a stable identifier for a pack set, covering sizes, prices and stock.
+func fingerprint(ps packSet) string {
+	parts := make([]string, len(ps.sizes))
+	for i, size := range ps.sizes {
+		parts[i] = strconv.Itoa(size)
+		if price := ps.price(i); price > 0 {
+			parts[i] += "@" + strconv.FormatFloat(price, 'g', -1, 64)
+		}
+		if stock := ps.stock(i); stock != unlimited {
+			parts[i] += "x" + strconv.Itoa(stock)
+		}
+	}
+	return strings.Join(parts, ",")
+}
@@ -0,0 +1,75 @@
+package optimizer
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestResultCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	c := newResultCache(2, 0)
+
+	c.put(cacheKey{fingerprint: "a", items: 1}, &result{totalItems: 1})
+	c.put(cacheKey{fingerprint: "a", items: 2}, &result{totalItems: 2})
+
+	_, ok := c.get(cacheKey{fingerprint: "a", items: 1})
+	assert.True(t, ok)
+
+	c.put(cacheKey{fingerprint: "a", items: 3}, &result{totalItems: 3})
+
+	_, ok = c.get(cacheKey{fingerprint: "a", items: 2})
+	assert.False(t, ok)
+	_, ok = c.get(cacheKey{fingerprint: "a", items: 1})
+	assert.True(t, ok)
+
+	stats := c.snapshot()
+	assert.Equal(t, uint64(2), stats.Hits)
+	assert.Equal(t, uint64(1), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 2, stats.Entries)
+	assert.Equal(t, 2, stats.Capacity)
+}
+
+func TestResultCache_Expires(t *testing.T) {
+	now := time.Unix(0, 0)
+	c := newResultCache(10, time.Minute)
+	c.now = func() time.Time { return now }
+
+	key := cacheKey{fingerprint: "a", items: 1}
+	c.put(key, &result{totalItems: 1})
+
+	now = now.Add(30 * time.Second)
+	_, ok := c.get(key)
+	assert.True(t, ok)
+
+	now = now.Add(31 * time.Second)
+	_, ok = c.get(key)
+	assert.False(t, ok)
+
+	stats := c.snapshot()
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 0, stats.Entries)
+}
+
+func TestResultCache_Disabled(t *testing.T) {
+	c := newResultCache(0, 0)
+
+	key := cacheKey{fingerprint: "a", items: 1}
+	c.put(key, &result{totalItems: 1})
+
+	_, ok := c.get(key)
+	assert.False(t, ok)
+	assert.Equal(t, 0, c.snapshot().Entries)
+}
+
+func TestResultCache_Reset(t *testing.T) {
+	c := newResultCache(10, 0)
+
+	key := cacheKey{fingerprint: "a", items: 1}
+	c.put(key, &result{totalItems: 1})
+	c.reset()
+
+	_, ok := c.get(key)
+	assert.False(t, ok)
+}
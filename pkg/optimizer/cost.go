@@ -0,0 +1,144 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"math/bits"
+	"strings"
+)
+
+// Names of the built-in cost strategies accepted by ParseCostFunction.
+const (
+	StrategyFewestItems = "fewest_items"
+	StrategyFewestPacks = "fewest_packs"
+	StrategyWeighted    = "weighted"
+	StrategyLowestCost  = "lowest_cost"
+)
+
+// costEpsilon is the tolerance below which two monetary totals are considered equal.
+const costEpsilon = 1e-9
+
+// Candidate summarises a packing so that a CostFunction can rank it.
+type Candidate struct {
+	TotalItems int
+	TotalPacks int
+	TotalCost  float64
+}
+
+// CostFunction ranks candidate packings for an order. Less reports whether a is strictly
+// preferable to b. Implementations must never prefer a candidate that has more items and
+// more packs than another, otherwise the solver may miss the optimum.
+type CostFunction interface {
+	Name() string
+	Less(a, b Candidate) bool
+}
+
+// FewestItems prefers the fewest items shipped, then the fewest packs. It is the default.
+var FewestItems CostFunction = fewestItemsCost{}
+
+// FewestPacks prefers the fewest packs, then the fewest items shipped.
+var FewestPacks CostFunction = fewestPacksCost{}
+
+type fewestItemsCost struct{}
+
+func (fewestItemsCost) Name() string { return StrategyFewestItems }
+
+func (fewestItemsCost) Less(a, b Candidate) bool {
+	if a.TotalItems != b.TotalItems {
+		return a.TotalItems < b.TotalItems
+	}
+	return a.TotalPacks < b.TotalPacks
+}
+
+type fewestPacksCost struct{}
+
+func (fewestPacksCost) Name() string { return StrategyFewestPacks }
+
+func (fewestPacksCost) Less(a, b Candidate) bool {
+	if a.TotalPacks != b.TotalPacks {
+		return a.TotalPacks < b.TotalPacks
+	}
+	return a.TotalItems < b.TotalItems
+}
+
+// Weighted scores a candidate as items + packWeight*packs, so saving one pack is worth up
+// to packWeight extra items. Equal scores prefer fewer packs.
+func Weighted(packWeight int) CostFunction {
+	return weightedCost{packWeight: packWeight}
+}
+
+type weightedCost struct {
+	packWeight int
+}
+
+func (w weightedCost) Name() string { return fmt.Sprintf("%s:%d", StrategyWeighted, w.packWeight) }
+
+func (w weightedCost) Less(a, b Candidate) bool {
+	hiA, loA := w.score(a)
+	hiB, loB := w.score(b)
+	if hiA != hiB {
+		return hiA < hiB
+	}
+	if loA != loB {
+		return loA < loB
+	}
+	if a.TotalPacks != b.TotalPacks {
+		return a.TotalPacks < b.TotalPacks
+	}
+	return a.TotalItems < b.TotalItems
+}
+
+// score returns items + packWeight*packs of c as a 128-bit two's complement integer, so
+// that huge weights or orders are ranked without overflowing. Items and packs are never
+// negative.
+func (w weightedCost) score(c Candidate) (hi int64, lo uint64) {
+	weight := uint64(w.packWeight)
+	if w.packWeight < 0 {
+		weight = -weight
+	}
+	high, low := bits.Mul64(weight, uint64(c.TotalPacks))
+	if w.packWeight < 0 {
+		var borrow uint64
+		low, borrow = bits.Sub64(0, low, 0)
+		high, _ = bits.Sub64(0, high, borrow)
+	}
+	low, carry := bits.Add64(low, uint64(c.TotalItems), 0)
+	return int64(high + carry), low
+}
+
+// LowestCost prefers the lowest total price, then the fewest items, then the fewest packs.
+// Sizes without a price count as free.
+var LowestCost CostFunction = cheapestCost{}
+
+type cheapestCost struct{}
+
+func (cheapestCost) Name() string { return StrategyLowestCost }
+
+func (cheapestCost) Less(a, b Candidate) bool {
+	if math.Abs(a.TotalCost-b.TotalCost) > costEpsilon {
+		return a.TotalCost < b.TotalCost
+	}
+	if a.TotalItems != b.TotalItems {
+		return a.TotalItems < b.TotalItems
+	}
+	return a.TotalPacks < b.TotalPacks
+}
+
+// ParseCostFunction returns the built-in strategy with the given name. packWeight is only
+// used by the weighted strategy and must not be negative. An empty name selects FewestItems.
+func ParseCostFunction(name string, packWeight int) (CostFunction, error) {
+	switch strings.ToLower(strings.TrimSpace(name)) {
+	case "", StrategyFewestItems:
+		return FewestItems, nil
+	case StrategyFewestPacks:
+		return FewestPacks, nil
+	case StrategyLowestCost:
+		return LowestCost, nil
+	case StrategyWeighted:
+		if packWeight < 0 {
+			return nil, fmt.Errorf("pack weight must not be negative")
+		}
+		return Weighted(packWeight), nil
+	}
+	return nil, fmt.Errorf("unknown strategy %q", name)
+}
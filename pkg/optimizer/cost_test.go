@@ -0,0 +1,135 @@
+package optimizer_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseCostFunction(t *testing.T) {
+	cf, err := optimizer.ParseCostFunction("", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.StrategyFewestItems, cf.Name())
+
+	cf, err = optimizer.ParseCostFunction("FEWEST_PACKS", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.StrategyFewestPacks, cf.Name())
+
+	cf, err = optimizer.ParseCostFunction("lowest_cost", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.StrategyLowestCost, cf.Name())
+
+	cf, err = optimizer.ParseCostFunction("weighted", 100)
+	assert.NoError(t, err)
+	assert.Equal(t, "weighted:100", cf.Name())
+
+	_, err = optimizer.ParseCostFunction("weighted", -1)
+	assert.Error(t, err)
+
+	_, err = optimizer.ParseCostFunction("cheapest", 0)
+	assert.Error(t, err)
+}
+
+func TestCostFunctions_Less(t *testing.T) {
+	fewerItems := optimizer.Candidate{TotalItems: 500, TotalPacks: 2}
+	fewerPacks := optimizer.Candidate{TotalItems: 1000, TotalPacks: 1}
+
+	assert.True(t, optimizer.FewestItems.Less(fewerItems, fewerPacks))
+	assert.False(t, optimizer.FewestItems.Less(fewerPacks, fewerItems))
+
+	assert.True(t, optimizer.FewestPacks.Less(fewerPacks, fewerItems))
+	assert.False(t, optimizer.FewestPacks.Less(fewerItems, fewerPacks))
+
+	assert.True(t, optimizer.Weighted(500).Less(fewerPacks, fewerItems))
+	assert.True(t, optimizer.Weighted(499).Less(fewerItems, fewerPacks))
+
+	huge := optimizer.Weighted(math.MaxInt / 2)
+	assert.True(t, huge.Less(fewerPacks, fewerItems))
+	assert.False(t, huge.Less(fewerItems, fewerPacks))
+	assert.True(t, optimizer.Weighted(math.MaxInt).Less(
+		optimizer.Candidate{TotalItems: math.MaxInt, TotalPacks: 2},
+		optimizer.Candidate{TotalItems: 0, TotalPacks: 3},
+	))
+	assert.True(t, optimizer.Weighted(-1).Less(fewerItems, fewerPacks))
+}
+
+func TestCalculate_CostFunctions(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 250), nil).Maybe()
+
+	tests := []struct {
+		name     string
+		cost     optimizer.CostFunction
+		expected []int
+	}{
+		{name: "fewest items", cost: optimizer.FewestItems, expected: []int{250, 250}},
+		{name: "fewest packs", cost: optimizer.FewestPacks, expected: []int{1000}},
+		{name: "weighted low", cost: optimizer.Weighted(300), expected: []int{250, 250}},
+		{name: "weighted high", cost: optimizer.Weighted(800), expected: []int{1000}},
+		{name: "weighted huge", cost: optimizer.Weighted(math.MaxInt / 2), expected: []int{1000}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel), optimizer.WithCostFunction(test.cost))
+			assert.Equal(t, test.expected, mustCalculate(t, opt, 251).PacksUsed)
+
+			opt = optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+			assert.Equal(t, test.expected, mustCalculate(t, opt, 251, optimizer.UsingCostFunction(test.cost)).PacksUsed)
+		})
+	}
+}
+
+func TestCalculate_CostFunctionCachedSeparately(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	assert.Equal(t, 500, mustCalculate(t, opt, 251).TotalItems)
+	assert.Equal(t, 1000, mustCalculate(t, opt, 251, optimizer.UsingCostFunction(optimizer.FewestPacks)).TotalItems)
+	assert.Equal(t, 500, mustCalculate(t, opt, 251).TotalItems)
+}
+
+func TestCalculate_LowestCost(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{
+		{Size: 5000, Enabled: true, Price: 40},
+		{Size: 1000, Enabled: true, Price: 5},
+		{Size: 250, Enabled: true, Price: 3},
+	}, nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 5000)
+	assert.Equal(t, []int{5000}, result.PacksUsed)
+	assert.Equal(t, []float64{40}, result.PackCosts)
+	assert.Equal(t, float64(40), result.TotalCost)
+
+	result = mustCalculate(t, opt, 5000, optimizer.UsingCostFunction(optimizer.LowestCost))
+	assert.Equal(t, []int{1000, 1000, 1000, 1000, 1000}, result.PacksUsed)
+	assert.Equal(t, []float64{5, 5, 5, 5, 5}, result.PackCosts)
+	assert.Equal(t, float64(25), result.TotalCost)
+	assert.Equal(t, 5000, result.TotalItems)
+
+	result = mustCalculate(t, opt, 4001, optimizer.UsingCostFunction(optimizer.LowestCost))
+	assert.Equal(t, []int{1000, 1000, 1000, 1000, 250}, result.PacksUsed)
+	assert.Equal(t, float64(23), result.TotalCost)
+}
+
+func TestCalculate_UnpricedHasNoPackCosts(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 501, optimizer.UsingCostFunction(optimizer.LowestCost))
+	assert.Equal(t, []int{500, 250}, result.PacksUsed)
+	assert.Nil(t, result.PackCosts)
+	assert.Equal(t, float64(0), result.TotalCost)
+}
@@ -0,0 +1,13 @@
+package optimizer
+
+import "errors"
+
+// Errors returned by Calculate. Callers should compare with errors.Is.
+var (
+	ErrNoSizes              = errors.New("no pack sizes configured")
+	ErrInvalidQuantity      = errors.New("items ordered must be greater than 0")
+	ErrQuantityTooLarge     = errors.New("items ordered is too large")
+	ErrInsufficientStock    = errors.New("insufficient stock to fulfil the order")
+	ErrInfeasible           = errors.New("no combination of packs satisfies the order")
+	ErrAlternativesTooLarge = errors.New("order too large to list alternatives")
+)
@@ -0,0 +1,68 @@
+package optimizer
+
+import (
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+)
+
+// maxHistory is the number of past catalog versions an Optimizer keeps loaded.
+const maxHistory = 16
+
+// snapshot is a pack set loaded for calculation, with the tables built for it and the
+// catalog version it was taken from.
+type snapshot struct {
+	packs       packSet
+	base        *baseTable
+	fingerprint string
+	version     int
+}
+
+// newSnapshot loads the enabled packs of catalog version version.
+func newSnapshot(packs []sizer.Pack, version int) *snapshot {
+	enabled := make([]sizer.Pack, 0, len(packs))
+	for _, pack := range packs {
+		if pack.Enabled {
+			enabled = append(enabled, pack)
+		}
+	}
+	ps := newPackSet(enabled)
+
+	return &snapshot{
+		packs:       ps,
+		base:        newBaseTable(ps),
+		fingerprint: fingerprint(ps),
+		version:     version,
+	}
+}
+
+// snapshotAt returns the pack set of catalog version version, or the current one when
+// version is not positive or is the version currently loaded. Versions never change once
+// written, so past ones are loaded once and kept, up to maxHistory of them.
+func (opt *Optimizer) snapshotAt(version int) (*snapshot, error) {
+	opt.mu.RLock()
+	loaded, past := opt.loaded, opt.history[version]
+	opt.mu.RUnlock()
+
+	if version <= 0 || version == loaded.version {
+		return loaded, nil
+	}
+	if past != nil {
+		return past, nil
+	}
+
+	v, err := opt.sizer.GetVersion(opt.catalog, version)
+	if err != nil {
+		return nil, err
+	}
+	past = newSnapshot(v.Packs, v.Number)
+
+	opt.mu.Lock()
+	if len(opt.history) >= maxHistory {
+		for number := range opt.history {
+			delete(opt.history, number)
+			break
+		}
+	}
+	opt.history[version] = past
+	opt.mu.Unlock()
+	return past, nil
+}
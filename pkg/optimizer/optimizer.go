@@ -2,148 +2,361 @@ package optimizer
 
 import (
 	"fmt"
+	"math"
 	"sort"
+	"sync"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 )
 
-// memo stores previously computed optimization results to avoid redundant calculations.
-var memo = make(map[int]*result)
-
 //go:generate mockgen -source=optimizer.go -destination=../../internal/handler/mocks/mock_optimizer.go -package=mocks
 
 // OptimizerInterface defines the behavior expected from any optimizer implementation.
 type OptimizerInterface interface {
 	Load() error
-	Calculate(itemsOrdered int) *OptimizationResult
+	Calculate(itemsOrdered int, options ...CalculateOption) (*OptimizationResult, error)
 	GetAllSizes() ([]int, error)
+	GetAllPacks() ([]sizer.Pack, error)
 	AddSize(size int) error
+	AddPack(pack sizer.Pack) error
+	UpdatePack(size int, update func(*sizer.Pack) error) error
+	SetEnabled(size int, enabled bool) error
+	ReplaceSizes(packs []sizer.Pack) error
+	MergePacks(packs []sizer.Pack) error
 	RemoveSize(size int) error
+	GetVersions() ([]sizer.Version, error)
+	GetVersion(number int) (sizer.Version, error)
+	RestoreVersion(number int) error
 }
 
-// Optimizer provides methods for calculating optimal packaging solutions.
+// Optimizer provides methods for calculating optimal packaging solutions for the pack
+// sizes of one catalog.
 type Optimizer struct {
 	sizer  sizer.SizerInterface
 	logger logger.Logger
-	sizes  []int
+	cache  *resultCache
+
+	catalog   string
+	cacheSize int
+	cacheTTL  time.Duration
+	cost      CostFunction
+	onChange  func(*Optimizer)
+
+	// loadMu serialises Load, so that the set of the latest read is always the one kept.
+	loadMu sync.Mutex
+
+	mu      sync.RWMutex
+	loaded  *snapshot
+	history map[int]*snapshot
 }
 
+// MaxListedPacks is the largest number of packs an OptimizationResult lists one by one in
+// PacksUsed. Larger results are only described by their Breakdown.
+const MaxListedPacks = 1000000
+
 // OptimizationResult holds the final output of a packaging optimization.
+// PackCosts is parallel to PacksUsed and only set when some size carries a price.
+// Breakdown groups the packs by size, largest first. PacksUsed and PackCosts are nil when
+// the result holds more than MaxListedPacks packs. Alternatives is only set when
+// requested with UsingAlternatives and lists the ranked combinations, the first being
+// the result itself. CatalogVersion is the catalog version whose pack sizes were used,
+// zero for a catalog that has never been changed.
 type OptimizationResult struct {
-	PacksUsed  []int `json:"packs_used"`
-	TotalItems int   `json:"total_items"`
-	TotalPacks int   `json:"total_packs"`
+	PacksUsed       []int                `json:"packs_used"`
+	PackCosts       []float64            `json:"pack_costs,omitempty"`
+	Breakdown       []PackCount          `json:"breakdown"`
+	ItemsRequested  int                  `json:"items_requested"`
+	TotalItems      int                  `json:"total_items"`
+	TotalPacks      int                  `json:"total_packs"`
+	TotalCost       float64              `json:"total_cost"`
+	OverfillItems   int                  `json:"overfill_items"`
+	OverfillPercent float64              `json:"overfill_percent"`
+	Alternatives    []OptimizationResult `json:"alternatives,omitempty"`
+	CatalogVersion  int                  `json:"catalog_version,omitempty"`
+}
+
+// PackCount is the number of packs of one size used by a result.
+type PackCount struct {
+	Size  int `json:"size"`
+	Count int `json:"count"`
 }
 
-// result is an internal struct used during recursive computation of packaging.
+// result is an internal struct holding a packaging computed by the solver, along with
+// the ranked alternatives when they were requested. repeat counts the largest packs
+// included in the totals but not listed in packs.
 type result struct {
-	packs      []int
-	totalItems int
+	packs        []int
+	repeat       int
+	totalItems   int
+	totalCost    float64
+	alternatives []*result
 }
 
 // New creates a new Optimizer instance and preloads the available pack sizes.
-func New(s sizer.SizerInterface, l logger.Logger) *Optimizer {
+func New(s sizer.SizerInterface, l logger.Logger, options ...Option) *Optimizer {
 	opt := &Optimizer{
-		sizer:  s,
-		logger: l,
+		sizer:     s,
+		logger:    l,
+		catalog:   sizer.DefaultCatalog,
+		cacheSize: DefaultCacheSize,
+		cacheTTL:  DefaultCacheTTL,
+		cost:      FewestItems,
 	}
+	for _, o := range options {
+		o(opt)
+	}
+	opt.cache = newResultCache(opt.cacheSize, opt.cacheTTL)
+	opt.loaded = newSnapshot(nil, 0)
+	opt.history = make(map[int]*snapshot)
+
 	if err := opt.Load(); err != nil {
 		l.Info(fmt.Sprintf("Error loading sizes: %v\n", err))
 	}
 	return opt
 }
 
-// Load retrieves and caches the available pack sizes from the sizer, sorted in descending order.
+// Load retrieves and caches the enabled pack sizes, prices and stock from the sizer,
+// sorted by size in descending order, along with the catalog version they belong to.
+// Sets without limited stock are also tabulated up to the point where their optimum
+// repeats, so that orders of any size are answered from that table. Concurrent loads run
+// one at a time, so that a slower load never replaces the set of a later one.
 func (opt *Optimizer) Load() error {
-	sizes, err := opt.sizer.GetAllSizes()
+	opt.loadMu.Lock()
+	defer opt.loadMu.Unlock()
+
+	current, err := opt.sizer.CurrentVersion(opt.catalog)
 	if err != nil {
 		return err
 	}
-	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
-	opt.sizes = sizes
+
+	loaded := newSnapshot(current.Packs, current.Number)
+
+	opt.mu.Lock()
+	opt.loaded = loaded
+	opt.mu.Unlock()
 	return nil
 }
 
-// Calculate returns the best combination of pack sizes for the given number of items.
-func (opt *Optimizer) Calculate(itemsOrdered int) *OptimizationResult {
-	if len(opt.sizes) == 0 || itemsOrdered <= 0 {
-		return &OptimizationResult{}
+// Calculate returns the best combination of pack sizes for the given number of items,
+// ranked by the optimizer's cost function unless a CalculateOption overrides it. Sizes
+// with limited stock are never used beyond what is on hand.
+//
+// It returns ErrInvalidQuantity for a non-positive quantity, ErrNoSizes when no pack size
+// is loaded, ErrInsufficientStock when the stock on hand cannot cover the order and
+// ErrInfeasible when no combination respects the requested Overfill. ErrQuantityTooLarge
+// is returned when the shipped total could overflow an int, or when the order is too
+// large to solve: past maxSolveTotals items only periodic cost functions over a set with
+// a baseTable are answered. Listing alternatives returns ErrAlternativesTooLarge when the
+// order is too large to rank, and calculating against a past catalog version returns
+// sizer.ErrVersionNotFound when the catalog has no such version.
+func (opt *Optimizer) Calculate(itemsOrdered int, options ...CalculateOption) (*OptimizationResult, error) {
+	calc := calculation{cost: opt.cost}
+	for _, o := range options {
+		o(&calc)
 	}
 
-	res := opt.discoverPackages(itemsOrdered)
-	if res == nil {
-		return &OptimizationResult{}
+	if itemsOrdered <= 0 {
+		return nil, ErrInvalidQuantity
 	}
 
-	return &OptimizationResult{
-		PacksUsed:  res.packs,
-		TotalItems: res.totalItems,
-		TotalPacks: len(res.packs),
+	loaded, err := opt.snapshotAt(calc.version)
+	if err != nil {
+		return nil, err
 	}
-}
+	ps, base, fp := loaded.packs, loaded.base, loaded.fingerprint
 
-// discoverPackages recursively determines the most efficient combination of pack sizes
-// that covers at least the requested number of items, minimizing totalItems and pack count.
-func (opt *Optimizer) discoverPackages(items int) *result {
-	if res, ok := memo[items]; ok {
-		return res
+	if len(ps.sizes) == 0 {
+		return nil, ErrNoSizes
+	}
+	if itemsOrdered > math.MaxInt-ps.sizes[0] {
+		return nil, ErrQuantityTooLarge
 	}
 
-	var best *result
-
-	for _, size := range opt.sizes {
-		newRemaining := items - size
-		var candidate *result
-
-		if newRemaining <= 0 {
-			candidate = &result{
-				packs:      []int{size},
-				totalItems: size,
+	extra := calc.overfill.allowance(itemsOrdered)
+	key := cacheKey{
+		fingerprint:  fp,
+		items:        itemsOrdered,
+		extra:        extra,
+		cost:         calc.cost.Name(),
+		alternatives: calc.alternatives,
+	}
+	res, ok := opt.cache.get(key)
+	if !ok {
+		if calc.alternatives > 0 {
+			alts, err := alternatives(ps, itemsOrdered, extra, calc.alternatives, calc.cost)
+			if err != nil {
+				return nil, err
 			}
-		} else {
-			next := opt.discoverPackages(newRemaining)
-			if next == nil {
-				continue
+			if len(alts) > 0 {
+				best := *alts[0]
+				best.alternatives = alts
+				res = &best
 			}
-			candidate = &result{
-				packs:      append([]int{size}, next.packs...),
-				totalItems: size + next.totalItems,
+		} else if base != nil && isPeriodic(calc.cost) {
+			res = base.solve(ps, itemsOrdered, extra, calc.cost)
+		} else {
+			res, err = solve(ps, itemsOrdered, extra, calc.cost)
+			if err != nil {
+				return nil, err
 			}
 		}
-
-		best = chooseBetter(best, candidate)
+		opt.cache.put(key, res)
+	}
+	if res == nil {
+		if !ps.covers(itemsOrdered) {
+			return nil, ErrInsufficientStock
+		}
+		return nil, ErrInfeasible
 	}
 
-	memo[items] = best
-	return best
+	out := present(ps, res, itemsOrdered)
+	out.CatalogVersion = loaded.version
+	for _, alt := range res.alternatives {
+		out.Alternatives = append(out.Alternatives, *present(ps, alt, itemsOrdered))
+	}
+	return out, nil
 }
 
-// chooseBetter compares two packaging results and returns the more efficient one.
-func chooseBetter(a, b *result) *result {
-	if a == nil {
-		return b
+// present converts a solver result for an order of items into an OptimizationResult.
+func present(ps packSet, res *result, items int) *OptimizationResult {
+	out := &OptimizationResult{
+		Breakdown:       breakdown(res.packs),
+		ItemsRequested:  items,
+		TotalItems:      res.totalItems,
+		TotalPacks:      len(res.packs) + res.repeat,
+		TotalCost:       res.totalCost,
+		OverfillItems:   res.totalItems - items,
+		OverfillPercent: overfillPercent(res.totalItems, items),
 	}
-	if b == nil {
-		return a
+	if res.repeat > 0 {
+		largest := ps.sizes[0]
+		if len(out.Breakdown) > 0 && out.Breakdown[0].Size == largest {
+			out.Breakdown[0].Count += res.repeat
+		} else {
+			out.Breakdown = append([]PackCount{{Size: largest, Count: res.repeat}}, out.Breakdown...)
+		}
 	}
-	if b.totalItems < a.totalItems {
-		return b
+	if out.TotalPacks > MaxListedPacks {
+		return out
 	}
-	if b.totalItems == a.totalItems && len(b.packs) < len(a.packs) {
-		return b
+
+	out.PacksUsed = make([]int, 0, out.TotalPacks)
+	for i := 0; i < res.repeat; i++ {
+		out.PacksUsed = append(out.PacksUsed, ps.sizes[0])
+	}
+	out.PacksUsed = append(out.PacksUsed, res.packs...)
+	if ps.prices != nil {
+		out.PackCosts = make([]float64, len(out.PacksUsed))
+		for i, size := range out.PacksUsed {
+			out.PackCosts[i] = ps.priceOf(size)
+		}
 	}
-	return a
+	return out
+}
+
+// breakdown groups packs, sorted in descending order, into one count per size.
+func breakdown(packs []int) []PackCount {
+	var counts []PackCount
+	for _, size := range packs {
+		if n := len(counts); n > 0 && counts[n-1].Size == size {
+			counts[n-1].Count++
+			continue
+		}
+		counts = append(counts, PackCount{Size: size, Count: 1})
+	}
+	return counts
+}
+
+// overfillPercent returns how many items beyond the order were shipped, as a percentage
+// of the order rounded to two decimals.
+func overfillPercent(total, items int) float64 {
+	return math.Round(float64(total-items)/float64(items)*10000) / 100
 }
 
-// GetAllSizes returns all available pack sizes.
+// Stats returns the hit, miss and eviction counters of the result cache.
+func (opt *Optimizer) Stats() Stats {
+	return opt.cache.snapshot()
+}
+
+// GetAllSizes returns all available pack sizes, sorted in descending order.
 func (opt *Optimizer) GetAllSizes() ([]int, error) {
-	return opt.sizer.GetAllSizes()
+	sizes, err := opt.sizer.GetAllSizes(opt.catalog)
+	if err != nil {
+		return nil, err
+	}
+	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	return sizes, nil
+}
+
+// GetAllPacks returns all pack records, disabled ones included, sorted by size in descending
+// order.
+func (opt *Optimizer) GetAllPacks() ([]sizer.Pack, error) {
+	packs, err := opt.sizer.GetAllPacks(opt.catalog)
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Size > packs[j].Size })
+	return packs, nil
 }
 
 // AddSize adds a new pack size to the system.
 func (opt *Optimizer) AddSize(size int) error {
-	err := opt.sizer.AddSize(size)
+	err := opt.sizer.AddSize(opt.catalog, size)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// AddPack adds or updates a pack size with its price and stock.
+func (opt *Optimizer) AddPack(pack sizer.Pack) error {
+	err := opt.sizer.AddPack(opt.catalog, pack)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// UpdatePack applies update to the record of an existing pack size and stores the result.
+func (opt *Optimizer) UpdatePack(size int, update func(*sizer.Pack) error) error {
+	err := opt.sizer.UpdatePack(opt.catalog, size, update)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// SetEnabled enables or disables an existing pack size. Disabled sizes stay stored but
+// are not used to fulfil orders.
+func (opt *Optimizer) SetEnabled(size int, enabled bool) error {
+	err := opt.sizer.SetEnabled(opt.catalog, size, enabled)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// ReplaceSizes makes packs the complete set of pack sizes. Calculations see either the
+// previous set or the new one, never a mix of both.
+func (opt *Optimizer) ReplaceSizes(packs []sizer.Pack) error {
+	err := opt.sizer.ReplaceSizes(opt.catalog, packs)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// MergePacks adds or replaces several pack sizes at once, keeping the others. Like
+// ReplaceSizes, calculations never see a partial merge.
+func (opt *Optimizer) MergePacks(packs []sizer.Pack) error {
+	err := opt.sizer.MergePacks(opt.catalog, packs)
 	if err != nil {
 		return err
 	}
@@ -153,7 +366,7 @@ func (opt *Optimizer) AddSize(size int) error {
 
 // RemoveSize deletes a pack size from the system.
 func (opt *Optimizer) RemoveSize(size int) error {
-	err := opt.sizer.RemoveSize(size)
+	err := opt.sizer.RemoveSize(opt.catalog, size)
 	if err != nil {
 		return err
 	}
@@ -161,7 +374,44 @@ func (opt *Optimizer) RemoveSize(size int) error {
 	return opt.reloadValues()
 }
 
+// GetVersions returns every version of the catalog, oldest first, without their packs.
+func (opt *Optimizer) GetVersions() ([]sizer.Version, error) {
+	return opt.sizer.GetVersions(opt.catalog)
+}
+
+// GetVersion returns a version of the catalog with its packs.
+func (opt *Optimizer) GetVersion(number int) (sizer.Version, error) {
+	return opt.sizer.GetVersion(opt.catalog, number)
+}
+
+// RestoreVersion makes the pack sizes of a past version the current ones, recording the
+// restore as a new version.
+func (opt *Optimizer) RestoreVersion(number int) error {
+	err := opt.sizer.RestoreVersion(opt.catalog, number)
+	if err != nil {
+		return err
+	}
+
+	return opt.reloadValues()
+}
+
+// reloadValues refreshes the size set and drops results computed for the previous one.
+// Entries are keyed by the size fingerprint, so a calculation still running against the
+// old set cannot store a result that the new set would ever read.
 func (opt *Optimizer) reloadValues() error {
-	memo = make(map[int]*result)
-	return opt.Load()
+	if err := opt.Load(); err != nil {
+		return err
+	}
+	opt.cache.reset()
+	if opt.onChange != nil {
+		opt.onChange(opt)
+	}
+	return nil
+}
+
+// unused reports whether the catalog of opt has neither enabled pack sizes nor history.
+func (opt *Optimizer) unused() bool {
+	opt.mu.RLock()
+	defer opt.mu.RUnlock()
+	return opt.loaded.version == 0 && len(opt.loaded.packs.sizes) == 0
 }
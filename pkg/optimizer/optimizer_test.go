@@ -2,12 +2,17 @@ package optimizer_test
 
 import (
 	"fmt"
+	"math"
+	"sync"
 	"testing"
+	"time"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -15,18 +20,95 @@ type MockSizer struct {
 	mock.Mock
 }
 
-func (m *MockSizer) GetAllSizes() ([]int, error) {
-	args := m.Called()
+// newMockSizer returns a MockSizer whose catalogs have never been changed unless a test
+// expects LatestVersion itself.
+func newMockSizer() *MockSizer {
+	m := new(MockSizer)
+	m.On("LatestVersion", mock.Anything).Return(0, nil).Maybe()
+	return m
+}
+
+func (m *MockSizer) LatestVersion(catalog string) (int, error) {
+	args := m.Called(catalog)
+	return args.Int(0), args.Error(1)
+}
+
+// CurrentVersion is answered from the GetAllPacks and LatestVersion expectations, like
+// the sizer reads both from one snapshot.
+func (m *MockSizer) CurrentVersion(catalog string) (sizer.Version, error) {
+	packs, err := m.GetAllPacks(catalog)
+	if err != nil {
+		return sizer.Version{}, err
+	}
+	latest, err := m.LatestVersion(catalog)
+	if err != nil {
+		return sizer.Version{}, err
+	}
+	return sizer.Version{Number: latest, Packs: packs}, nil
+}
+
+func (m *MockSizer) GetVersions(catalog string) ([]sizer.Version, error) {
+	args := m.Called(catalog)
+	return args.Get(0).([]sizer.Version), args.Error(1)
+}
+
+func (m *MockSizer) GetVersion(catalog string, number int) (sizer.Version, error) {
+	args := m.Called(catalog, number)
+	return args.Get(0).(sizer.Version), args.Error(1)
+}
+
+func (m *MockSizer) RestoreVersion(catalog string, number int) error {
+	args := m.Called(catalog, number)
+	return args.Error(0)
+}
+
+func (m *MockSizer) GetAllSizes(catalog string) ([]int, error) {
+	args := m.Called(catalog)
 	return args.Get(0).([]int), args.Error(1)
 }
 
-func (m *MockSizer) AddSize(size int) error {
-	args := m.Called(size)
+func (m *MockSizer) GetAllPacks(catalog string) ([]sizer.Pack, error) {
+	args := m.Called(catalog)
+	return args.Get(0).([]sizer.Pack), args.Error(1)
+}
+
+func (m *MockSizer) GetPack(catalog string, size int) (sizer.Pack, error) {
+	args := m.Called(catalog, size)
+	return args.Get(0).(sizer.Pack), args.Error(1)
+}
+
+func (m *MockSizer) UpdatePack(catalog string, size int, update func(*sizer.Pack) error) error {
+	args := m.Called(catalog, size, update)
+	return args.Error(0)
+}
+
+func (m *MockSizer) SetEnabled(catalog string, size int, enabled bool) error {
+	args := m.Called(catalog, size, enabled)
+	return args.Error(0)
+}
+
+func (m *MockSizer) ReplaceSizes(catalog string, packs []sizer.Pack) error {
+	args := m.Called(catalog, packs)
 	return args.Error(0)
 }
 
-func (m *MockSizer) RemoveSize(size int) error {
-	args := m.Called(size)
+func (m *MockSizer) MergePacks(catalog string, packs []sizer.Pack) error {
+	args := m.Called(catalog, packs)
+	return args.Error(0)
+}
+
+func (m *MockSizer) AddSize(catalog string, size int) error {
+	args := m.Called(catalog, size)
+	return args.Error(0)
+}
+
+func (m *MockSizer) AddPack(catalog string, pack sizer.Pack) error {
+	args := m.Called(catalog, pack)
+	return args.Error(0)
+}
+
+func (m *MockSizer) RemoveSize(catalog string, size int) error {
+	args := m.Called(catalog, size)
 	return args.Error(0)
 }
 
@@ -35,9 +117,18 @@ func (m *MockSizer) Close() error {
 	return args.Error(0)
 }
 
+func packsOf(sizes ...int) []sizer.Pack {
+	packs := make([]sizer.Pack, len(sizes))
+	for i, size := range sizes {
+		packs[i] = sizer.NewPack(size)
+	}
+	return packs
+}
+
 func TestNewOptimizer(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{500, 1000, 250}, nil).Maybe()
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(500, 1000, 250), nil).Maybe()
+	mockSizer.On("GetAllSizes", sizer.DefaultCatalog).Return([]int{500, 1000, 250}, nil).Maybe()
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
@@ -48,8 +139,9 @@ func TestNewOptimizer(t *testing.T) {
 }
 
 func TestLoad(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{1000, 250, 500}, nil).Maybe()
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 250, 500), nil).Maybe()
+	mockSizer.On("GetAllSizes", sizer.DefaultCatalog).Return([]int{1000, 250, 500}, nil).Maybe()
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 	err := opt.Load()
@@ -60,10 +152,17 @@ func TestLoad(t *testing.T) {
 	mockSizer.AssertExpectations(t)
 }
 
+func mustCalculate(t *testing.T, opt *optimizer.Optimizer, items int, options ...optimizer.CalculateOption) *optimizer.OptimizationResult {
+	t.Helper()
+	result, err := opt.Calculate(items, options...)
+	require.NoError(t, err)
+	return result
+}
+
 func TestCalculate(t *testing.T) {
-	mockSizer := new(MockSizer)
+	mockSizer := newMockSizer()
 
-	mockSizer.On("GetAllSizes").Return([]int{1000, 500, 250}, nil).Maybe()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
 	tests := []struct {
@@ -86,28 +185,13 @@ func TestCalculate(t *testing.T) {
 				TotalPacks: 2,
 			},
 		},
-		{
-			itemsOrdered: 0,
-			expected: &optimizer.OptimizationResult{
-				PacksUsed:  nil,
-				TotalItems: 0,
-				TotalPacks: 0,
-			},
-		},
-		{
-			itemsOrdered: -100,
-			expected: &optimizer.OptimizationResult{
-				PacksUsed:  nil,
-				TotalItems: 0,
-				TotalPacks: 0,
-			},
-		},
 	}
 
 	for _, test := range tests {
 		t.Run(fmt.Sprintf("itemsOrdered=%d", test.itemsOrdered), func(t *testing.T) {
-			result := opt.Calculate(test.itemsOrdered)
+			result, err := opt.Calculate(test.itemsOrdered)
 
+			assert.NoError(t, err)
 			assert.Equal(t, test.expected.PacksUsed, result.PacksUsed)
 			assert.Equal(t, test.expected.TotalItems, result.TotalItems)
 			assert.Equal(t, test.expected.TotalPacks, result.TotalPacks)
@@ -118,9 +202,9 @@ func TestCalculate(t *testing.T) {
 }
 
 func TestAddSize(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{1000, 500, 250}, nil)
-	mockSizer.On("AddSize", 300).Return(nil)
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil)
+	mockSizer.On("AddSize", sizer.DefaultCatalog, 300).Return(nil)
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
@@ -131,9 +215,9 @@ func TestAddSize(t *testing.T) {
 }
 
 func TestRemoveSize(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{1000, 500, 250}, nil)
-	mockSizer.On("RemoveSize", 300).Return(nil)
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil)
+	mockSizer.On("RemoveSize", sizer.DefaultCatalog, 300).Return(nil)
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
@@ -144,8 +228,9 @@ func TestRemoveSize(t *testing.T) {
 }
 
 func TestGetAllSizes(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{250, 500, 1000}, nil).Maybe()
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(250, 500, 1000), nil).Maybe()
+	mockSizer.On("GetAllSizes", sizer.DefaultCatalog).Return([]int{250, 500, 1000}, nil).Maybe()
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
@@ -157,34 +242,435 @@ func TestGetAllSizes(t *testing.T) {
 }
 
 func TestCalculate_EmptySizes(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{}, nil).Maybe()
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(), nil).Maybe()
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
-	result := opt.Calculate(501)
-	assert.NotNil(t, result)
-	assert.Equal(t, 0, result.TotalPacks)
-	assert.Nil(t, result.PacksUsed)
+	result, err := opt.Calculate(501)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, optimizer.ErrNoSizes)
 
 	mockSizer.AssertExpectations(t)
 }
 
 func TestCalculate_InvalidItemsOrdered(t *testing.T) {
-	mockSizer := new(MockSizer)
-	mockSizer.On("GetAllSizes").Return([]int{500, 1000, 250}, nil).Maybe()
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(500, 1000, 250), nil).Maybe()
 
 	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
 
-	result := opt.Calculate(-100)
-	assert.NotNil(t, result)
-	assert.Equal(t, 0, result.TotalPacks)
-	assert.Nil(t, result.PacksUsed)
+	result, err := opt.Calculate(-100)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, optimizer.ErrInvalidQuantity)
+
+	result, err = opt.Calculate(0)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, optimizer.ErrInvalidQuantity)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestCalculate_LargeOrder(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 5000001)
+	assert.Equal(t, 5000250, result.TotalItems)
+	assert.Equal(t, 5001, result.TotalPacks)
+	assert.Equal(t, 250, result.PacksUsed[len(result.PacksUsed)-1])
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestCalculate_UnevenSizes(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(23, 31, 53), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 500000)
+	assert.Equal(t, 500000, result.TotalItems)
+	assert.Equal(t, 9438, result.TotalPacks)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestCalculate_IsolatedPerInstance(t *testing.T) {
+	sizerA := newMockSizer()
+	sizerA.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+	sizerB := newMockSizer()
+	sizerB.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(300), nil).Maybe()
+
+	optA := optimizer.New(sizerA, logger.New(zapcore.DebugLevel))
+	optB := optimizer.New(sizerB, logger.New(zapcore.DebugLevel))
+
+	assert.Equal(t, 750, mustCalculate(t, optA, 501).TotalItems)
+	assert.Equal(t, 600, mustCalculate(t, optB, 501).TotalItems)
+	assert.Equal(t, []int{300, 300}, mustCalculate(t, optB, 501).PacksUsed)
+}
+
+func TestCalculate_ReloadInvalidatesCache(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250, 1), nil)
+	mockSizer.On("AddSize", sizer.DefaultCatalog, 1).Return(nil)
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Equal(t, 750, mustCalculate(t, opt, 501).TotalItems)
+
+	assert.Nil(t, opt.AddSize(1))
+	assert.Equal(t, 501, mustCalculate(t, opt, 501).TotalItems)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestCalculate_Concurrent(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil)
+	mockSizer.On("AddSize", sizer.DefaultCatalog, 300).Return(nil)
+	mockSizer.On("RemoveSize", sizer.DefaultCatalog, 300).Return(nil)
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			for items := 1; items <= 200; items++ {
+				result, err := opt.Calculate(items * (i + 1))
+				if assert.NoError(t, err) {
+					assert.GreaterOrEqual(t, result.TotalItems, items*(i+1))
+				}
+			}
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for i := 0; i < 50; i++ {
+			assert.Nil(t, opt.AddSize(300))
+			assert.Nil(t, opt.RemoveSize(300))
+		}
+	}()
+	wg.Wait()
+}
+
+func TestLoad_KeepsLatestRead(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Run(func(mock.Arguments) {
+		close(entered)
+		<-release
+	}).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250, 1), nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	// A load that read the old set before a later load read the new one must not replace
+	// it once it finishes.
+	var wg sync.WaitGroup
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, opt.Load())
+	}()
+	<-entered
+	go func() {
+		defer wg.Done()
+		assert.NoError(t, opt.Load())
+	}()
+	time.Sleep(10 * time.Millisecond)
+	close(release)
+	wg.Wait()
+
+	assert.Equal(t, 501, mustCalculate(t, opt, 501).TotalItems)
+	mockSizer.AssertExpectations(t)
+}
+
+func TestStats(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel), optimizer.WithCacheSize(1), optimizer.WithCacheTTL(time.Hour))
+
+	mustCalculate(t, opt, 501)
+	mustCalculate(t, opt, 501)
+	mustCalculate(t, opt, 1500)
+
+	stats := opt.Stats()
+	assert.Equal(t, uint64(1), stats.Hits)
+	assert.Equal(t, uint64(2), stats.Misses)
+	assert.Equal(t, uint64(1), stats.Evictions)
+	assert.Equal(t, 1, stats.Entries)
+	assert.Equal(t, 1, stats.Capacity)
+}
+
+func TestCalculate_LimitedStock(t *testing.T) {
+	one, none := 1, 0
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{
+		{Size: 1000, Enabled: true, Stock: &none},
+		{Size: 500, Enabled: true, Stock: &one},
+		{Size: 250, Enabled: true},
+	}, nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 1000)
+	assert.Equal(t, []int{500, 250, 250}, result.PacksUsed)
+	assert.Equal(t, 1000, result.TotalItems)
+}
+
+func TestCalculate_InsufficientStock(t *testing.T) {
+	two := 2
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{
+		{Size: 1000, Enabled: true, Stock: &two},
+		{Size: 500, Enabled: true, Stock: &two},
+	}, nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result, err := opt.Calculate(3001)
+	assert.Nil(t, result)
+	assert.ErrorIs(t, err, optimizer.ErrInsufficientStock)
+
+	result = mustCalculate(t, opt, 3000)
+	assert.Equal(t, []int{1000, 1000, 500, 500}, result.PacksUsed)
+}
+
+func TestCalculate_Breakdown(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 2251)
+	assert.Equal(t, []int{1000, 1000, 500}, result.PacksUsed)
+	assert.Equal(t, []optimizer.PackCount{{Size: 1000, Count: 2}, {Size: 500, Count: 1}}, result.Breakdown)
+	assert.Equal(t, 2251, result.ItemsRequested)
+	assert.Equal(t, 249, result.OverfillItems)
+	assert.Equal(t, 11.06, result.OverfillPercent)
+
+	result = mustCalculate(t, opt, 1500)
+	assert.Equal(t, 0, result.OverfillItems)
+	assert.Equal(t, float64(0), result.OverfillPercent)
+}
+
+func TestCalculate_Alternatives(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 1000, optimizer.UsingAlternatives(3))
+	assert.Equal(t, []int{1000}, result.PacksUsed)
+	if assert.Len(t, result.Alternatives, 3) {
+		assert.Equal(t, []int{1000}, result.Alternatives[0].PacksUsed)
+		assert.Equal(t, []int{500, 500}, result.Alternatives[1].PacksUsed)
+		assert.Equal(t, []int{500, 250, 250}, result.Alternatives[2].PacksUsed)
+		assert.Equal(t, 1000, result.Alternatives[2].ItemsRequested)
+	}
+
+	result = mustCalculate(t, opt, 1000)
+	assert.Nil(t, result.Alternatives)
+
+	// Only four combinations cover 1000 items without a superfluous pack.
+	result = mustCalculate(t, opt, 1000, optimizer.UsingAlternatives(50))
+	assert.Len(t, result.Alternatives, 4)
+}
 
-	result = opt.Calculate(0)
-	assert.NotNil(t, result)
-	assert.Equal(t, 0, result.TotalPacks)
+func TestCalculate_AlternativesTooLarge(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	_, err := opt.Calculate(100000000, optimizer.UsingAlternatives(3))
+	assert.ErrorIs(t, err, optimizer.ErrAlternativesTooLarge)
+}
+
+func TestCalculate_HugeOrder(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(5000, 2000, 1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 1000000000000001)
+	assert.Equal(t, 1000000000000250, result.TotalItems)
+	assert.Equal(t, 200000000001, result.TotalPacks)
+	assert.Equal(t, []optimizer.PackCount{{Size: 5000, Count: 200000000000}, {Size: 250, Count: 1}}, result.Breakdown)
 	assert.Nil(t, result.PacksUsed)
 
+	result = mustCalculate(t, opt, 12001)
+	assert.Equal(t, []int{5000, 5000, 2000, 250}, result.PacksUsed)
+
+	_, err := opt.Calculate(math.MaxInt)
+	assert.ErrorIs(t, err, optimizer.ErrQuantityTooLarge)
+
+	// Cost functions without a period are solved over the whole order, which is capped.
+	_, err = opt.Calculate(1000000000, optimizer.UsingCostFunction(optimizer.LowestCost))
+	assert.ErrorIs(t, err, optimizer.ErrQuantityTooLarge)
+}
+
+func TestCalculate_HugePackSize(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(20000000), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	for _, cf := range []optimizer.CostFunction{optimizer.FewestItems, optimizer.LowestCost} {
+		result := mustCalculate(t, opt, 1, optimizer.UsingCostFunction(cf))
+		assert.Equal(t, []int{20000000}, result.PacksUsed)
+	}
+}
+
+func TestCalculate_HugeOrderWithoutPeriod(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(5000, 4999), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 10001)
+	assert.Equal(t, []int{4999, 4999, 4999}, result.PacksUsed)
+
+	_, err := opt.Calculate(1000000000)
+	assert.ErrorIs(t, err, optimizer.ErrQuantityTooLarge)
+}
+
+func TestLoad_SkipsDisabledSizes(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{
+		{Size: 1000, Enabled: false},
+		{Size: 500, Enabled: true},
+		{Size: 250, Enabled: true},
+	}, nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 1000)
+	assert.Equal(t, []int{500, 500}, result.PacksUsed)
+
+	packs, err := opt.GetAllPacks()
+	assert.NoError(t, err)
+	assert.Len(t, packs, 3)
+}
+
+func TestUpdatePack(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500), nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Equal(t, []int{1000}, mustCalculate(t, opt, 1000).PacksUsed)
+
+	mockSizer.On("UpdatePack", sizer.DefaultCatalog, 1000, mock.Anything).Return(nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{{Size: 1000}, sizer.NewPack(500)}, nil).Once()
+
+	assert.NoError(t, opt.UpdatePack(1000, func(p *sizer.Pack) error {
+		p.Enabled = false
+		return nil
+	}))
+	assert.Equal(t, []int{500, 500}, mustCalculate(t, opt, 1000).PacksUsed)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestSetEnabled(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500), nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Equal(t, []int{1000}, mustCalculate(t, opt, 1000).PacksUsed)
+
+	mockSizer.On("SetEnabled", sizer.DefaultCatalog, 1000, false).Return(nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return([]sizer.Pack{{Size: 1000}, sizer.NewPack(500)}, nil).Once()
+	assert.NoError(t, opt.SetEnabled(1000, false))
+	assert.Equal(t, []int{500, 500}, mustCalculate(t, opt, 1000).PacksUsed)
+
+	mockSizer.On("SetEnabled", sizer.DefaultCatalog, 750, true).Return(sizer.ErrSizeNotFound).Once()
+	assert.ErrorIs(t, opt.SetEnabled(750, true), sizer.ErrSizeNotFound)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestReplaceSizes(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Equal(t, []int{500, 250}, mustCalculate(t, opt, 600).PacksUsed)
+
+	replacement := packsOf(1200, 600, 300)
+	mockSizer.On("ReplaceSizes", sizer.DefaultCatalog, replacement).Return(nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(replacement, nil).Once()
+	assert.NoError(t, opt.ReplaceSizes(replacement))
+	assert.Equal(t, []int{600}, mustCalculate(t, opt, 600).PacksUsed)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestMergePacks(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500), nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Equal(t, []int{500}, mustCalculate(t, opt, 300).PacksUsed)
+
+	merged := packsOf(300)
+	mockSizer.On("MergePacks", sizer.DefaultCatalog, merged).Return(nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 300), nil).Once()
+	assert.NoError(t, opt.MergePacks(merged))
+	assert.Equal(t, []int{300}, mustCalculate(t, opt, 300).PacksUsed)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestCalculate_Version(t *testing.T) {
+	mockSizer := new(MockSizer)
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1200, 600, 300), nil).Once()
+	mockSizer.On("LatestVersion", sizer.DefaultCatalog).Return(3, nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	result := mustCalculate(t, opt, 500)
+	assert.Equal(t, []int{600}, result.PacksUsed)
+	assert.Equal(t, 3, result.CatalogVersion)
+	assert.Equal(t, 3, mustCalculate(t, opt, 500, optimizer.UsingVersion(3)).CatalogVersion)
+
+	mockSizer.On("GetVersion", sizer.DefaultCatalog, 1).Return(sizer.Version{Number: 1, Packs: packsOf(1000, 500, 250)}, nil).Once()
+	for i := 0; i < 2; i++ {
+		result = mustCalculate(t, opt, 500, optimizer.UsingVersion(1))
+		assert.Equal(t, []int{500}, result.PacksUsed)
+		assert.Equal(t, 1, result.CatalogVersion)
+	}
+
+	mockSizer.On("GetVersion", sizer.DefaultCatalog, 9).Return(sizer.Version{}, sizer.ErrVersionNotFound).Once()
+	_, err := opt.Calculate(500, optimizer.UsingVersion(9))
+	assert.ErrorIs(t, err, sizer.ErrVersionNotFound)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestRestoreVersion(t *testing.T) {
+	mockSizer := new(MockSizer)
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1200, 600, 300), nil).Once()
+	mockSizer.On("LatestVersion", sizer.DefaultCatalog).Return(3, nil).Once()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	mockSizer.On("RestoreVersion", sizer.DefaultCatalog, 1).Return(nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Once()
+	mockSizer.On("LatestVersion", sizer.DefaultCatalog).Return(4, nil).Once()
+	assert.NoError(t, opt.RestoreVersion(1))
+
+	result := mustCalculate(t, opt, 500)
+	assert.Equal(t, []int{500}, result.PacksUsed)
+	assert.Equal(t, 4, result.CatalogVersion)
+
 	mockSizer.AssertExpectations(t)
 }
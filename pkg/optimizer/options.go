@@ -0,0 +1,96 @@
+package optimizer
+
+import "time"
+
+// Option configures an Optimizer at construction time.
+type Option func(*Optimizer)
+
+// WithCatalog selects the catalog whose pack sizes the Optimizer serves. It defaults to
+// sizer.DefaultCatalog.
+func WithCatalog(v string) Option {
+	return func(opt *Optimizer) {
+		opt.catalog = v
+	}
+}
+
+// WithCacheSize bounds the number of cached results. A non-positive value disables caching.
+func WithCacheSize(v int) Option {
+	return func(opt *Optimizer) {
+		opt.cacheSize = v
+	}
+}
+
+// WithCacheTTL sets how long a cached result stays valid. Zero keeps entries until evicted.
+func WithCacheTTL(v time.Duration) Option {
+	return func(opt *Optimizer) {
+		opt.cacheTTL = v
+	}
+}
+
+// WithCostFunction sets the default objective used to rank packings. Nil keeps FewestItems.
+func WithCostFunction(v CostFunction) Option {
+	return func(opt *Optimizer) {
+		if v != nil {
+			opt.cost = v
+		}
+	}
+}
+
+// withOnChange sets a function called after each change the Optimizer makes to its
+// catalog, once its pack sizes are reloaded.
+func withOnChange(v func(*Optimizer)) Option {
+	return func(opt *Optimizer) {
+		opt.onChange = v
+	}
+}
+
+// CalculateOption adjusts a single Calculate call.
+type CalculateOption func(*calculation)
+
+// calculation holds the per-request settings of a Calculate call.
+type calculation struct {
+	cost         CostFunction
+	overfill     Overfill
+	alternatives int
+	version      int
+}
+
+// UsingCostFunction ranks this calculation with v instead of the optimizer default.
+// Nil keeps the default.
+func UsingCostFunction(v CostFunction) CalculateOption {
+	return func(c *calculation) {
+		if v != nil {
+			c.cost = v
+		}
+	}
+}
+
+// UsingOverfill limits how far this calculation may exceed the ordered quantity.
+func UsingOverfill(v Overfill) CalculateOption {
+	return func(c *calculation) {
+		c.overfill = v
+	}
+}
+
+// UsingAlternatives lists up to v distinct combinations ranked by the cost function,
+// best first. Values above MaxAlternatives are capped and non-positive values list none.
+func UsingAlternatives(v int) CalculateOption {
+	return func(c *calculation) {
+		switch {
+		case v > MaxAlternatives:
+			c.alternatives = MaxAlternatives
+		case v > 0:
+			c.alternatives = v
+		default:
+			c.alternatives = 0
+		}
+	}
+}
+
+// UsingVersion calculates against the pack sizes of catalog version v instead of the
+// current ones, reproducing a past quote. Non-positive values use the current sizes.
+func UsingVersion(v int) CalculateOption {
+	return func(c *calculation) {
+		c.version = v
+	}
+}
@@ -0,0 +1,71 @@
+package optimizer
+
+import (
+	"fmt"
+	"math"
+	"strings"
+)
+
+// Overfill modes accepted by ParseOverfill.
+const (
+	ModeAny                = ""
+	ModeExact              = "exact"
+	ModeMaxOverfillItems   = "max_overfill_items"
+	ModeMaxOverfillPercent = "max_overfill_percent"
+)
+
+// Overfill limits how many items beyond the ordered quantity a packing may ship. Limit
+// is a number of items or a percentage of the order, depending on Mode.
+type Overfill struct {
+	Mode  string
+	Limit int
+}
+
+// ParseOverfill validates a mode name and its limit. An empty mode accepts any overfill.
+func ParseOverfill(mode string, limit int) (Overfill, error) {
+	mode = strings.ToLower(strings.TrimSpace(mode))
+	switch mode {
+	case ModeAny, ModeExact:
+		return Overfill{Mode: mode}, nil
+	case ModeMaxOverfillItems, ModeMaxOverfillPercent:
+		if limit < 0 {
+			return Overfill{}, fmt.Errorf("overfill limit must not be negative")
+		}
+		return Overfill{Mode: mode, Limit: limit}, nil
+	}
+	return Overfill{}, fmt.Errorf("unknown mode %q", mode)
+}
+
+// allowance returns how many extra items may be shipped for an order of items, or
+// unlimited when any overfill is accepted. Percentages round down, and a percentage of
+// more items than an int holds accepts any overfill.
+func (o Overfill) allowance(items int) int {
+	switch o.Mode {
+	case ModeExact:
+		return 0
+	case ModeMaxOverfillItems:
+		return o.Limit
+	case ModeMaxOverfillPercent:
+		return percentOf(items, o.Limit)
+	}
+	return unlimited
+}
+
+// percentOf returns percent percent of items, rounded down, or unlimited when it does not
+// fit in an int. Both must be non-negative. items*percent is split by the hundreds of
+// each so that no intermediate product overflows.
+func percentOf(items, percent int) int {
+	if percent == 0 {
+		return 0
+	}
+	hundreds, rest := items/100, items%100
+	if hundreds > math.MaxInt/percent {
+		return unlimited
+	}
+	whole := hundreds * percent
+	part := rest*(percent/100) + rest*(percent%100)/100
+	if whole > math.MaxInt-part {
+		return unlimited
+	}
+	return whole + part
+}
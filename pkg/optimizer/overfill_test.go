@@ -0,0 +1,90 @@
+package optimizer_test
+
+import (
+	"math"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseOverfill(t *testing.T) {
+	o, err := optimizer.ParseOverfill("", 0)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.ModeAny, o.Mode)
+
+	o, err = optimizer.ParseOverfill("EXACT", 5)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.Overfill{Mode: optimizer.ModeExact}, o)
+
+	o, err = optimizer.ParseOverfill("max_overfill_percent", 10)
+	assert.NoError(t, err)
+	assert.Equal(t, optimizer.Overfill{Mode: optimizer.ModeMaxOverfillPercent, Limit: 10}, o)
+
+	_, err = optimizer.ParseOverfill("max_overfill_items", -1)
+	assert.Error(t, err)
+
+	_, err = optimizer.ParseOverfill("roughly", 0)
+	assert.Error(t, err)
+}
+
+func TestCalculate_Overfill(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	tests := []struct {
+		name     string
+		items    int
+		overfill optimizer.Overfill
+		err      error
+		total    int
+	}{
+		{name: "any", items: 501, overfill: optimizer.Overfill{}, total: 750},
+		{name: "exact fits", items: 1250, overfill: optimizer.Overfill{Mode: optimizer.ModeExact}, total: 1250},
+		{name: "exact infeasible", items: 501, overfill: optimizer.Overfill{Mode: optimizer.ModeExact}, err: optimizer.ErrInfeasible},
+		{name: "items fits", items: 501, overfill: optimizer.Overfill{Mode: optimizer.ModeMaxOverfillItems, Limit: 249}, total: 750},
+		{name: "items infeasible", items: 501, overfill: optimizer.Overfill{Mode: optimizer.ModeMaxOverfillItems, Limit: 248}, err: optimizer.ErrInfeasible},
+		{name: "percent fits", items: 501, overfill: optimizer.Overfill{Mode: optimizer.ModeMaxOverfillPercent, Limit: 50}, total: 750},
+		{name: "percent infeasible", items: 501, overfill: optimizer.Overfill{Mode: optimizer.ModeMaxOverfillPercent, Limit: 40}, err: optimizer.ErrInfeasible},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			result, err := opt.Calculate(test.items, optimizer.UsingOverfill(test.overfill))
+			if test.err != nil {
+				assert.ErrorIs(t, err, test.err)
+				assert.Nil(t, result)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, test.total, result.TotalItems)
+		})
+	}
+}
+
+func TestCalculate_OverfillPercentOfHugeOrder(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Maybe()
+
+	opt := optimizer.New(mockSizer, logger.New(zapcore.DebugLevel))
+
+	for _, test := range []struct {
+		items   int
+		percent int
+	}{
+		{items: 1 << 60, percent: 10},
+		{items: math.MaxInt - 1000, percent: 1},
+		{items: math.MaxInt - 1000, percent: 1000},
+		{items: math.MaxInt - 1000, percent: math.MaxInt},
+	} {
+		result, err := opt.Calculate(test.items, optimizer.UsingOverfill(optimizer.Overfill{Mode: optimizer.ModeMaxOverfillPercent, Limit: test.percent}))
+		if assert.NoError(t, err, test) {
+			assert.Less(t, result.OverfillItems, 250)
+		}
+	}
+}
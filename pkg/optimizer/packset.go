@@ -0,0 +1,114 @@
+package optimizer
+
+import (
+	"sort"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+)
+
+// unlimited is the stock of a size that can be used any number of times.
+const unlimited = -1
+
+// packSet is the immutable size set an Optimizer solves against. sizes are sorted in
+// descending order; prices and stocks are parallel to sizes, prices is nil when no size
+// carries a price and stocks is nil when every size is unlimited.
+type packSet struct {
+	sizes  []int
+	prices []float64
+	stocks []int
+}
+
+// newPackSet builds a packSet from the sizer records, sorted by size in descending order.
+func newPackSet(packs []sizer.Pack) packSet {
+	packs = append([]sizer.Pack(nil), packs...)
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Size > packs[j].Size })
+
+	ps := packSet{
+		sizes:  make([]int, len(packs)),
+		prices: make([]float64, len(packs)),
+		stocks: make([]int, len(packs)),
+	}
+	priced, bounded := false, false
+	for i, pack := range packs {
+		ps.sizes[i] = pack.Size
+		ps.prices[i] = pack.Price
+		ps.stocks[i] = unlimited
+		if pack.Stock != nil {
+			ps.stocks[i] = *pack.Stock
+			bounded = true
+		}
+		priced = priced || pack.Price > 0
+	}
+	if !priced {
+		ps.prices = nil
+	}
+	if !bounded {
+		ps.stocks = nil
+	}
+	return ps
+}
+
+// bounded reports whether at least one size has a limited stock.
+func (ps packSet) bounded() bool {
+	return ps.stocks != nil
+}
+
+// covers reports whether the stock on hand could ship at least items, ignoring any cost.
+func (ps packSet) covers(items int) bool {
+	capacity := 0
+	for i, size := range ps.sizes {
+		switch stock := ps.stock(i); {
+		case stock == unlimited:
+			return true
+		case stock > 0:
+			if stock >= (items+size-1)/size {
+				return true
+			}
+			capacity += stock * size
+		}
+	}
+	return capacity >= items
+}
+
+// stock returns how many packs of the i-th size are on hand, or unlimited.
+func (ps packSet) stock(i int) int {
+	if ps.stocks == nil {
+		return unlimited
+	}
+	return ps.stocks[i]
+}
+
+// priceOf returns the unit price of size, zero when unpriced or unknown.
+func (ps packSet) priceOf(size int) float64 {
+	for i, s := range ps.sizes {
+		if s == size {
+			return ps.price(i)
+		}
+	}
+	return 0
+}
+
+// price returns the unit price of the i-th size, zero when unpriced.
+func (ps packSet) price(i int) float64 {
+	if ps.prices == nil {
+		return 0
+	}
+	return ps.prices[i]
+}
+
+// below returns the index of the first size smaller than items, len(sizes) when none is.
+func (ps packSet) below(items int) int {
+	return sort.Search(len(ps.sizes), func(i int) bool { return ps.sizes[i] < items })
+}
+
+// from returns the pack set of the sizes from the i-th on.
+func (ps packSet) from(i int) packSet {
+	sub := packSet{sizes: ps.sizes[i:]}
+	if ps.prices != nil {
+		sub.prices = ps.prices[i:]
+	}
+	if ps.stocks != nil {
+		sub.stocks = ps.stocks[i:]
+	}
+	return sub
+}
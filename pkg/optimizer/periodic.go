@@ -0,0 +1,118 @@
+package optimizer
+
+// maxBaseTotals bounds the totals tabulated while looking for the period of a pack set.
+// Sets without a period in that range are solved order by order, up to maxSolveTotals.
+const maxBaseTotals = 1 << 22
+
+// periodic is implemented by cost functions that may be answered from a baseTable. They
+// must rank two combinations of the same total by the fewest packs alone, and rank two
+// candidates the same way after adding one largest pack to both.
+type periodic interface {
+	periodic() bool
+}
+
+func (fewestItemsCost) periodic() bool { return true }
+
+func (fewestPacksCost) periodic() bool { return true }
+
+func (w weightedCost) periodic() bool { return w.packWeight >= 0 }
+
+// isPeriodic reports whether cf may be answered from a baseTable.
+func isPeriodic(cf CostFunction) bool {
+	p, ok := cf.(periodic)
+	return ok && p.periodic()
+}
+
+// baseTable is the solver table of an unbounded pack set, kept up to the point where it
+// repeats with the largest size. Every total from start on holds the combination stored
+// for one largest pack fewer, plus that pack.
+type baseTable struct {
+	tb    table
+	start int
+}
+
+// newBaseTable tabulates an unbounded pack set until it repeats with the largest size L.
+// It returns nil for sets with limited stock, or when no period shows up within
+// maxBaseTotals.
+//
+// The table is built with FewestItems, which for a single total keeps the fewest packs
+// like every periodic cost function. Write g(T) for that count, infinite when T is
+// unreachable. Once g(T) = g(T-L)+1 holds for L consecutive totals from X, it holds for
+// every T >= X: g(T) = 1 + min g(T-s) over the sizes s, each T-s lies in [T-L, T) where
+// the relation already holds, so g(T) = 1 + min g(T-L-s) = 1 + g(T-L). fill tries L first
+// and keeps it unless something is strictly better, so the combination stored for T is
+// then the one for T-L plus one L, which reconstruct walks back the same way. Such a run
+// always exists: a fewest-packs combination holding L or more smaller packs has a subset
+// summing to a multiple of L that fewer largest packs could replace, so past L-1 times
+// the second largest size every optimum holds a largest pack.
+func newBaseTable(ps packSet) *baseTable {
+	if len(ps.sizes) == 0 || ps.bounded() {
+		return nil
+	}
+	largest := ps.sizes[0]
+
+	tb := table{packs: []int32{0}}
+	if ps.prices != nil {
+		tb.costs = []float64{0}
+	}
+	grow := func() {
+		tb.packs = append(tb.packs, unreachable)
+		if tb.costs != nil {
+			tb.costs = append(tb.costs, 0)
+		}
+	}
+
+	run := 0
+	for total := 1; total < maxBaseTotals; total++ {
+		grow()
+		tb.fill(ps, total, FewestItems)
+		if total < largest || !repeats(tb, total, largest) {
+			run = 0
+			continue
+		}
+		if run++; run < largest {
+			continue
+		}
+
+		start := total - largest + 1
+		for total++; total < start+2*largest; total++ {
+			grow()
+			tb.fill(ps, total, FewestItems)
+		}
+		return &baseTable{tb: tb, start: start}
+	}
+	return nil
+}
+
+// repeats reports whether total holds one pack more than total-largest, or both are
+// unreachable.
+func repeats(tb table, total, largest int) bool {
+	if !tb.reachable(total) || !tb.reachable(total-largest) {
+		return tb.reachable(total) == tb.reachable(total-largest)
+	}
+	return tb.packs[total] == tb.packs[total-largest]+1
+}
+
+// solve answers an order exactly like solve does for a periodic cost function. Orders
+// past the tabulated range drop whole largest packs until they fall back into
+// [start, start+L): that shifts every total of the window by the same multiple of L,
+// which keeps their ranking, and the dropped packs are added back to the result.
+func (b *baseTable) solve(ps packSet, items, extra int, cf CostFunction) *result {
+	largest := ps.sizes[0]
+	repeat := 0
+	if items >= b.start+largest {
+		repeat = (items - b.start) / largest
+	}
+
+	base := items - repeat*largest
+	best := b.tb.pick(base, window(base, largest, extra), cf)
+	if best < 0 {
+		return nil
+	}
+
+	res := reconstruct(ps, b.tb, best)
+	res.repeat = repeat
+	res.totalItems += repeat * largest
+	res.totalCost += float64(repeat) * ps.price(0)
+	return res
+}
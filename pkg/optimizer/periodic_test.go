@@ -0,0 +1,67 @@
+package optimizer
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBaseTable_MatchesSolve(t *testing.T) {
+	rng := rand.New(rand.NewSource(3))
+	costs := []CostFunction{FewestItems, FewestPacks, Weighted(0), Weighted(7)}
+
+	for run := 0; run < 60; run++ {
+		var packs []sizer.Pack
+		for _, size := range rng.Perm(60)[:1+rng.Intn(4)] {
+			packs = append(packs, sizer.Pack{Size: size + 2, Price: float64(rng.Intn(3))})
+		}
+		ps := newPackSet(packs)
+		base := newBaseTable(ps)
+		require.NotNil(t, base)
+
+		largest := ps.sizes[0]
+		for _, cf := range costs {
+			for probe := 0; probe < 20; probe++ {
+				items := 1 + rng.Intn(base.start+6*largest)
+				extra := []int{unlimited, 0, rng.Intn(largest)}[rng.Intn(3)]
+
+				t.Run(fmt.Sprintf("%d/%s/%d/%d", run, cf.Name(), items, extra), func(t *testing.T) {
+					want := mustSolve(t, ps, items, extra, cf)
+					got := base.solve(ps, items, extra, cf)
+					if want == nil {
+						assert.Nil(t, got)
+						return
+					}
+					if !assert.NotNil(t, got) {
+						return
+					}
+
+					listed := got.packs
+					for i := 0; i < got.repeat; i++ {
+						listed = append([]int{largest}, listed...)
+					}
+					assert.Equal(t, want.packs, listed)
+					assert.Equal(t, want.totalItems, got.totalItems)
+					assert.InDelta(t, want.totalCost, got.totalCost, 1e-6)
+				})
+			}
+		}
+	}
+}
+
+func TestBaseTable_Bounded(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 500, Stock: stockOf(3)}, {Size: 250}})
+	assert.Nil(t, newBaseTable(ps))
+}
+
+func TestIsPeriodic(t *testing.T) {
+	assert.True(t, isPeriodic(FewestItems))
+	assert.True(t, isPeriodic(FewestPacks))
+	assert.True(t, isPeriodic(Weighted(3)))
+	assert.False(t, isPeriodic(Weighted(-1)))
+	assert.False(t, isPeriodic(LowestCost))
+}
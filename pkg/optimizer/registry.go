@@ -0,0 +1,122 @@
+package optimizer
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+)
+
+//go:generate mockgen -source=registry.go -destination=../../internal/handler/mocks/mock_registry.go -package=mocks
+
+// RegistryInterface resolves the optimizer serving a catalog.
+type RegistryInterface interface {
+	Get(catalog string) (OptimizerInterface, error)
+	Stats() map[string]Stats
+}
+
+// Registry holds one Optimizer per catalog, all sharing the same sizer. Optimizers are
+// built on first use with the registry's options. Catalogs without pack sizes or history,
+// such as any ID a client makes up, are not kept until a change is made to them, so that
+// the registry only grows with the catalogs actually stored.
+type Registry struct {
+	sizer   sizer.SizerInterface
+	logger  logger.Logger
+	options []Option
+
+	mu         sync.Mutex
+	optimizers map[string]*entry
+}
+
+// entry is the Optimizer of a catalog, built once by the first Get of the catalog. done is
+// closed once opt is set.
+type entry struct {
+	once sync.Once
+	done chan struct{}
+	opt  *Optimizer
+}
+
+// NewRegistry creates an empty Registry whose optimizers read s and are configured with
+// options.
+func NewRegistry(s sizer.SizerInterface, l logger.Logger, options ...Option) *Registry {
+	return &Registry{
+		sizer:      s,
+		logger:     l,
+		options:    options,
+		optimizers: make(map[string]*entry),
+	}
+}
+
+// Get returns the Optimizer of catalog, building and loading it on first use. It returns
+// sizer.ErrInvalidCatalog for an invalid catalog ID. Loading runs outside the lock of the
+// registry, so the first use of a catalog never holds up the others.
+func (r *Registry) Get(catalog string) (OptimizerInterface, error) {
+	if err := sizer.CheckCatalog(catalog); err != nil {
+		return nil, err
+	}
+
+	r.mu.Lock()
+	e, ok := r.optimizers[catalog]
+	if !ok {
+		e = &entry{done: make(chan struct{})}
+		r.optimizers[catalog] = e
+	}
+	r.mu.Unlock()
+
+	e.once.Do(func() {
+		defer close(e.done)
+		options := append(append([]Option(nil), r.options...), WithCatalog(catalog), withOnChange(r.changed))
+		e.opt = New(r.sizer, r.logger, options...)
+		if e.opt.unused() {
+			r.mu.Lock()
+			if r.optimizers[catalog] == e {
+				delete(r.optimizers, catalog)
+			}
+			r.mu.Unlock()
+		}
+	})
+	return e.opt, nil
+}
+
+// Stats returns the result cache statistics of every catalog the registry keeps, by
+// catalog ID. Catalogs still loading are left out.
+func (r *Registry) Stats() map[string]Stats {
+	r.mu.Lock()
+	optimizers := make(map[string]*Optimizer, len(r.optimizers))
+	for catalog, e := range r.optimizers {
+		select {
+		case <-e.done:
+			optimizers[catalog] = e.opt
+		default:
+		}
+	}
+	r.mu.Unlock()
+
+	stats := make(map[string]Stats, len(optimizers))
+	for catalog, opt := range optimizers {
+		stats[catalog] = opt.Stats()
+	}
+	return stats
+}
+
+// changed is called by an Optimizer of the registry after each change to its catalog. An
+// Optimizer that was not kept is kept from then on, and if another one was built for the
+// catalog meanwhile, that one is reloaded so that it does not miss the change.
+func (r *Registry) changed(opt *Optimizer) {
+	r.mu.Lock()
+	e, ok := r.optimizers[opt.catalog]
+	if !ok {
+		e = &entry{done: make(chan struct{}), opt: opt}
+		e.once.Do(func() { close(e.done) })
+		r.optimizers[opt.catalog] = e
+	}
+	r.mu.Unlock()
+
+	<-e.done
+	if e.opt != opt {
+		if err := e.opt.reloadValues(); err != nil {
+			r.logger.Info(fmt.Sprintf("Error reloading catalog %s: %v", opt.catalog, err))
+		}
+	}
+}
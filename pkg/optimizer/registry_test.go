@@ -0,0 +1,151 @@
+package optimizer_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/optimizer"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestRegistry_Get(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", "bolts").Return(packsOf(40, 20), nil).Once()
+	mockSizer.On("GetAllPacks", sizer.DefaultCatalog).Return(packsOf(1000, 500, 250), nil).Once()
+
+	registry := optimizer.NewRegistry(mockSizer, logger.New(zapcore.DebugLevel))
+
+	bolts, err := registry.Get("bolts")
+	require.NoError(t, err)
+	again, err := registry.Get("bolts")
+	require.NoError(t, err)
+	assert.Same(t, bolts, again)
+
+	result, err := bolts.Calculate(50)
+	require.NoError(t, err)
+	assert.Equal(t, []int{40, 20}, result.PacksUsed)
+
+	def, err := registry.Get(sizer.DefaultCatalog)
+	require.NoError(t, err)
+	result, err = def.Calculate(50)
+	require.NoError(t, err)
+	assert.Equal(t, []int{250}, result.PacksUsed)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestRegistry_InvalidCatalog(t *testing.T) {
+	registry := optimizer.NewRegistry(newMockSizer(), logger.New(zapcore.DebugLevel))
+
+	_, err := registry.Get("../packs")
+	assert.ErrorIs(t, err, sizer.ErrInvalidCatalog)
+}
+
+func TestRegistry_KeepsStoredCatalogsOnly(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", "made-up").Return([]sizer.Pack{}, nil).Twice()
+	mockSizer.On("GetAllPacks", "bolts").Return([]sizer.Pack{}, nil).Once()
+	mockSizer.On("GetAllPacks", "bolts").Return(packsOf(40, 20), nil).Once()
+	mockSizer.On("GetAllPacks", "bolts").Return(packsOf(40, 20, 10), nil).Twice()
+	mockSizer.On("AddSize", "bolts", 10).Return(nil).Once()
+	mockSizer.On("GetAllPacks", "nuts").Return([]sizer.Pack{}, nil).Once()
+	mockSizer.On("GetAllPacks", "nuts").Return(packsOf(8), nil).Once()
+	mockSizer.On("AddSize", "nuts", 8).Return(nil).Once()
+
+	registry := optimizer.NewRegistry(mockSizer, logger.New(zapcore.DebugLevel))
+
+	// A catalog without sizes or history is built again on every use, never kept.
+	first, err := registry.Get("made-up")
+	require.NoError(t, err)
+	again, err := registry.Get("made-up")
+	require.NoError(t, err)
+	assert.NotSame(t, first, again)
+
+	// A catalog is kept once it has sizes. A change made through an optimizer built while
+	// it had none reloads the kept one.
+	early, err := registry.Get("bolts")
+	require.NoError(t, err)
+	kept, err := registry.Get("bolts")
+	require.NoError(t, err)
+	assert.NotSame(t, early, kept)
+	require.NoError(t, early.AddSize(10))
+	again, err = registry.Get("bolts")
+	require.NoError(t, err)
+	assert.Same(t, kept, again)
+	result, err := kept.Calculate(10)
+	require.NoError(t, err)
+	assert.Equal(t, []int{10}, result.PacksUsed)
+
+	// The first change to a catalog keeps the optimizer it was made through.
+	nuts, err := registry.Get("nuts")
+	require.NoError(t, err)
+	require.NoError(t, nuts.AddSize(8))
+	again, err = registry.Get("nuts")
+	require.NoError(t, err)
+	assert.Same(t, nuts, again)
+
+	mockSizer.AssertExpectations(t)
+}
+
+func TestRegistry_LoadsOutsideLock(t *testing.T) {
+	entered, release := make(chan struct{}), make(chan struct{})
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", "slow").Return(packsOf(40, 20), nil).Run(func(mock.Arguments) {
+		close(entered)
+		<-release
+	}).Once()
+	mockSizer.On("GetAllPacks", "bolts").Return(packsOf(40, 20), nil).Once()
+
+	registry := optimizer.NewRegistry(mockSizer, logger.New(zapcore.DebugLevel))
+
+	slow := make(chan optimizer.OptimizerInterface)
+	go func() {
+		opt, err := registry.Get("slow")
+		assert.NoError(t, err)
+		slow <- opt
+	}()
+	<-entered
+
+	// Other catalogs are served while the first load of one is running.
+	bolts, err := registry.Get("bolts")
+	require.NoError(t, err)
+	assert.NotNil(t, bolts)
+
+	close(release)
+	select {
+	case opt := <-slow:
+		again, err := registry.Get("slow")
+		require.NoError(t, err)
+		assert.Same(t, opt, again)
+	case <-time.After(time.Second):
+		t.Fatal("first load of a catalog never finished")
+	}
+}
+
+func TestRegistry_Stats(t *testing.T) {
+	mockSizer := newMockSizer()
+	mockSizer.On("GetAllPacks", "bolts").Return(packsOf(40, 20), nil).Once()
+	mockSizer.On("GetAllPacks", "made-up").Return([]sizer.Pack{}, nil).Once()
+
+	registry := optimizer.NewRegistry(mockSizer, logger.New(zapcore.DebugLevel))
+	assert.Empty(t, registry.Stats())
+
+	bolts, err := registry.Get("bolts")
+	require.NoError(t, err)
+	for i := 0; i < 2; i++ {
+		_, err = bolts.Calculate(50)
+		require.NoError(t, err)
+	}
+	_, err = registry.Get("made-up")
+	require.NoError(t, err)
+
+	stats := registry.Stats()
+	assert.Len(t, stats, 1)
+	assert.Equal(t, uint64(1), stats["bolts"].Hits)
+	assert.Equal(t, uint64(1), stats["bolts"].Misses)
+}
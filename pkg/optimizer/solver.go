@@ -0,0 +1,232 @@
+package optimizer
+
+import (
+	"math"
+	"sort"
+)
+
+// unreachable marks a total that cannot be composed exactly from the pack sizes.
+const unreachable = math.MaxInt32
+
+// maxSolveTotals bounds the totals the solver tabulates for one order, which keeps its
+// table to a few hundred megabytes at most. Larger orders are answered from a baseTable
+// or refused.
+const maxSolveTotals = 1 << 24
+
+// table holds, for every exact total, the best combination found by the solver.
+// costs is nil when the pack set is unpriced.
+type table struct {
+	packs []int32
+	costs []float64
+}
+
+// reachable reports whether some combination adds up to exactly total.
+func (tb table) reachable(total int) bool {
+	return tb.packs[total] != unreachable
+}
+
+// candidate describes the combination stored for total.
+func (tb table) candidate(total int) Candidate {
+	c := Candidate{
+		TotalItems: total,
+		TotalPacks: int(tb.packs[total]),
+	}
+	if tb.costs != nil {
+		c.TotalCost = tb.costs[total]
+	}
+	return c
+}
+
+// extend describes the combination stored for total with n more packs of the i-th size.
+func (tb table) extend(ps packSet, total, i, n int) Candidate {
+	c := tb.candidate(total)
+	c.TotalItems += n * ps.sizes[i]
+	c.TotalPacks += n
+	c.TotalCost += float64(n) * ps.price(i)
+	return c
+}
+
+// set stores c as the combination for its total.
+func (tb table) set(c Candidate) {
+	tb.packs[c.TotalItems] = int32(c.TotalPacks)
+	if tb.costs != nil {
+		tb.costs[c.TotalItems] = c.TotalCost
+	}
+}
+
+// newTable allocates a table for totals below limit where only zero is reachable.
+func newTable(ps packSet, limit int) table {
+	tb := table{packs: make([]int32, limit)}
+	if ps.prices != nil {
+		tb.costs = make([]float64, limit)
+	}
+	for total := 1; total < limit; total++ {
+		tb.packs[total] = unreachable
+	}
+	return tb
+}
+
+// pick returns the total in [items, limit) whose stored combination ranks best under
+// cf, or -1 when none is reachable.
+func (tb table) pick(items, limit int, cf CostFunction) int {
+	best := -1
+	for total := items; total < limit; total++ {
+		if !tb.reachable(total) {
+			continue
+		}
+		if best < 0 || cf.Less(tb.candidate(total), tb.candidate(best)) {
+			best = total
+		}
+	}
+	return best
+}
+
+// solve finds the combination of pack sizes that covers at least items and ranks best
+// under cf, shipping at most extra items beyond the order unless extra is unlimited.
+// The sizes in ps must be positive and the prices non-negative. It returns nil when no
+// combination satisfies those limits, and ErrQuantityTooLarge when the table would
+// exceed maxSolveTotals.
+//
+// A size of at least items covers the order on its own, and adding packs to it only
+// adds items and packs, so such sizes are only tried as a single pack. The others are
+// solved with an iterative bottom-up dynamic program over exact totals. Any packing of
+// them reaching items+largest or more, largest being the largest size below items,
+// still covers the order after dropping one pack, so under a cost function that never
+// prefers more items and more packs no optimal total can reach that bound and the
+// table never grows past it. For every total the best combination reaching it exactly
+// is kept, and cf picks the winner among those totals and the single packs. Time is
+// O(items*len(sizes)) and memory O(items), with no recursion. Pack sets with limited
+// stock are handed to solveBounded.
+func solve(ps packSet, items, extra int, cf CostFunction) (*result, error) {
+	if len(ps.sizes) == 0 || items <= 0 {
+		return nil, nil
+	}
+
+	n := ps.below(items)
+	single := singlePack(ps, n, items, extra, cf)
+	small := ps.from(n)
+	if len(small.sizes) == 0 {
+		return single, nil
+	}
+
+	res, err := solveTable(small, items, extra, cf)
+	if err != nil {
+		return nil, err
+	}
+	if res == nil || (single != nil && cf.Less(single.candidate(), res.candidate())) {
+		return single, nil
+	}
+	return res, nil
+}
+
+// solveTable solves with a table of exact totals, for a pack set whose sizes are all
+// smaller than items.
+func solveTable(ps packSet, items, extra int, cf CostFunction) (*result, error) {
+	if ps.bounded() {
+		return solveBounded(ps, items, extra, cf)
+	}
+
+	limit := window(items, ps.sizes[0], extra)
+	if limit > maxSolveTotals {
+		return nil, ErrQuantityTooLarge
+	}
+	tb := build(ps, limit, cf)
+
+	best := tb.pick(items, limit, cf)
+	if best < 0 {
+		return nil, nil
+	}
+	return reconstruct(ps, tb, best), nil
+}
+
+// singlePack returns the single pack of one of the first n sizes of ps, each at least
+// items, that ranks best under cf within extra and stock, or nil when none does.
+func singlePack(ps packSet, n, items, extra int, cf CostFunction) *result {
+	var best *result
+	for i := n - 1; i >= 0; i-- {
+		size := ps.sizes[i]
+		if ps.stock(i) == 0 || (extra != unlimited && size-items > extra) {
+			continue
+		}
+		res := &result{packs: []int{size}, totalItems: size, totalCost: ps.price(i)}
+		if best == nil || cf.Less(res.candidate(), best.candidate()) {
+			best = res
+		}
+	}
+	return best
+}
+
+// candidate describes the combination of res for a cost function.
+func (res *result) candidate() Candidate {
+	return Candidate{TotalItems: res.totalItems, TotalPacks: len(res.packs) + res.repeat, TotalCost: res.totalCost}
+}
+
+// window returns the exclusive upper bound on the totals worth tabulating for an order
+// of items, given the largest usable size and the allowed extra items.
+func window(items, largest, extra int) int {
+	limit := items + largest
+	if extra != unlimited && extra < largest-1 {
+		limit = items + extra + 1
+	}
+	return limit
+}
+
+// build fills the table for every total below limit.
+func build(ps packSet, limit int, cf CostFunction) table {
+	tb := newTable(ps, limit)
+	for total := 1; total < limit; total++ {
+		tb.fill(ps, total, cf)
+	}
+	return tb
+}
+
+// fill stores the best combination reaching total, given every smaller total. Sizes are
+// tried largest first and only a strictly better combination replaces the stored one.
+// Combinations reaching the same total are compared with cf, which keeps the optimum as
+// long as cf ranks two combinations the same way after adding an identical pack to both.
+func (tb table) fill(ps packSet, total int, cf CostFunction) {
+	var best Candidate
+	found := false
+	for i, size := range ps.sizes {
+		if size > total || !tb.reachable(total-size) {
+			continue
+		}
+		c := tb.extend(ps, total-size, i, 1)
+		if !found || cf.Less(c, best) {
+			best, found = c, true
+		}
+	}
+	if found {
+		tb.set(best)
+	}
+}
+
+// reconstruct walks the table back from total, taking at each step the largest pack
+// whose predecessor explains the stored combination, and returns the packs in
+// descending order.
+func reconstruct(ps packSet, tb table, total int) *result {
+	res := &result{
+		packs:      make([]int, 0, tb.packs[total]),
+		totalItems: total,
+	}
+	if tb.costs != nil {
+		res.totalCost = tb.costs[total]
+	}
+
+	for total > 0 {
+		want := tb.candidate(total)
+		for i, size := range ps.sizes {
+			if size > total || !tb.reachable(total-size) {
+				continue
+			}
+			if tb.extend(ps, total-size, i, 1) == want {
+				res.packs = append(res.packs, size)
+				total -= size
+				break
+			}
+		}
+	}
+
+	sort.Sort(sort.Reverse(sort.IntSlice(res.packs)))
+	return res
+}
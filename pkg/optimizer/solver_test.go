@@ -0,0 +1,177 @@
+package optimizer
+
+import (
+	"fmt"
+	"math/rand"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func stockOf(n int) *int {
+	return &n
+}
+
+// mustSolve calls solve and fails the test on an error.
+func mustSolve(t *testing.T, ps packSet, items, extra int, cf CostFunction) *result {
+	res, err := solve(ps, items, extra, cf)
+	require.NoError(t, err)
+	return res
+}
+
+// bruteForce enumerates every combination within stock and overfill and returns the
+// best candidate.
+func bruteForce(ps packSet, items, extra int, cf CostFunction) (Candidate, bool) {
+	limits := make([]int, len(ps.sizes))
+	for i, size := range ps.sizes {
+		limits[i] = (items + ps.sizes[0]) / size
+		if stock := ps.stock(i); stock != unlimited && stock < limits[i] {
+			limits[i] = stock
+		}
+	}
+
+	var best Candidate
+	found := false
+	counts := make([]int, len(ps.sizes))
+	var walk func(i int)
+	walk = func(i int) {
+		if i == len(ps.sizes) {
+			c := Candidate{}
+			for j, n := range counts {
+				c.TotalItems += n * ps.sizes[j]
+				c.TotalPacks += n
+				c.TotalCost += float64(n) * ps.price(j)
+			}
+			if c.TotalItems < items || (extra != unlimited && c.TotalItems > items+extra) {
+				return
+			}
+			if !found || cf.Less(c, best) {
+				best, found = c, true
+			}
+			return
+		}
+		for n := 0; n <= limits[i]; n++ {
+			counts[i] = n
+			walk(i + 1)
+		}
+	}
+	walk(0)
+	return best, found
+}
+
+func TestSolve_MatchesBruteForce(t *testing.T) {
+	rng := rand.New(rand.NewSource(1))
+	costs := []CostFunction{FewestItems, FewestPacks, Weighted(7), LowestCost}
+
+	for run := 0; run < 200; run++ {
+		var packs []sizer.Pack
+		for _, size := range rng.Perm(40)[:1+rng.Intn(3)] {
+			pack := sizer.Pack{Size: size + 3, Price: float64(rng.Intn(20))}
+			if rng.Intn(4) > 0 {
+				pack.Stock = stockOf(rng.Intn(5))
+			}
+			packs = append(packs, pack)
+		}
+		ps := newPackSet(packs)
+		items := 1 + rng.Intn(120)
+		extra := []int{unlimited, 0, rng.Intn(10)}[rng.Intn(3)]
+
+		for _, cf := range costs {
+			t.Run(fmt.Sprintf("%d/%s", run, cf.Name()), func(t *testing.T) {
+				want, ok := bruteForce(ps, items, extra, cf)
+				res := mustSolve(t, ps, items, extra, cf)
+				if !ok {
+					assert.Nil(t, res)
+					return
+				}
+				if !assert.NotNil(t, res) {
+					return
+				}
+
+				got := Candidate{TotalItems: res.totalItems, TotalPacks: len(res.packs), TotalCost: res.totalCost}
+				assert.False(t, cf.Less(want, got), "want %+v, got %+v", want, got)
+
+				sum := 0
+				used := make(map[int]int)
+				for _, size := range res.packs {
+					sum += size
+					used[size]++
+				}
+				assert.Equal(t, res.totalItems, sum)
+				for i, size := range ps.sizes {
+					if stock := ps.stock(i); stock != unlimited {
+						assert.LessOrEqual(t, used[size], stock)
+					}
+				}
+			})
+		}
+	}
+}
+
+func TestSolveBounded_InsufficientStock(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{
+		{Size: 500, Stock: stockOf(1)},
+		{Size: 250, Stock: stockOf(1)},
+	})
+
+	assert.Nil(t, mustSolve(t, ps, 751, unlimited, FewestItems))
+	assert.Equal(t, 750, mustSolve(t, ps, 750, unlimited, FewestItems).totalItems)
+}
+
+func TestSolveBounded_ZeroStockOnly(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 500, Stock: stockOf(0)}})
+	assert.Nil(t, mustSolve(t, ps, 1, unlimited, FewestItems))
+}
+
+func TestSolve_TooLarge(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 500, Price: 4}, {Size: 250, Price: 3}})
+
+	_, err := solve(ps, 1000000000, unlimited, LowestCost)
+	assert.ErrorIs(t, err, ErrQuantityTooLarge)
+	_, err = solve(ps, maxSolveTotals, 0, LowestCost)
+	assert.ErrorIs(t, err, ErrQuantityTooLarge)
+}
+
+func TestSolveBounded_TooLarge(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 500, Stock: stockOf(10)}, {Size: 250}})
+
+	_, err := solve(ps, 1000000000, unlimited, FewestItems)
+	assert.ErrorIs(t, err, ErrQuantityTooLarge)
+
+	// Limited stock keeps the table to what is on hand.
+	res := mustSolve(t, newPackSet([]sizer.Pack{{Size: 500, Stock: stockOf(10)}}), 4999, unlimited, FewestItems)
+	assert.Equal(t, 5000, res.totalItems)
+}
+
+func TestSolve_PackLargerThanCap(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 20000000, Price: 9}, {Size: 500, Price: 4}, {Size: 250, Price: 3}})
+
+	res := mustSolve(t, ps, 1, unlimited, FewestItems)
+	assert.Equal(t, []int{250}, res.packs)
+
+	res = mustSolve(t, ps, 3000, unlimited, FewestPacks)
+	assert.Equal(t, []int{20000000}, res.packs)
+	res = mustSolve(t, ps, 3000, 100, FewestPacks)
+	assert.Equal(t, []int{500, 500, 500, 500, 500, 500}, res.packs)
+
+	res = mustSolve(t, newPackSet([]sizer.Pack{{Size: 20000000}}), 1, unlimited, LowestCost)
+	assert.Equal(t, []int{20000000}, res.packs)
+
+	_, err := solve(ps, 19999999, unlimited, FewestItems)
+	assert.ErrorIs(t, err, ErrQuantityTooLarge)
+}
+
+func TestSolveBounded_PackLargerThanCap(t *testing.T) {
+	ps := newPackSet([]sizer.Pack{{Size: 20000000, Stock: stockOf(2)}, {Size: 500, Stock: stockOf(1)}, {Size: 250}})
+
+	res := mustSolve(t, ps, 1, unlimited, FewestItems)
+	assert.Equal(t, []int{250}, res.packs)
+	res = mustSolve(t, ps, 3000, unlimited, FewestPacks)
+	assert.Equal(t, []int{20000000}, res.packs)
+
+	res = mustSolve(t, newPackSet([]sizer.Pack{{Size: 20000000, Stock: stockOf(1)}}), 1, unlimited, FewestItems)
+	assert.Equal(t, []int{20000000}, res.packs)
+	assert.Nil(t, mustSolve(t, newPackSet([]sizer.Pack{{Size: 20000000, Stock: stockOf(0)}}), 1, unlimited, FewestItems))
+}
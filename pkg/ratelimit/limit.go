@@ -0,0 +1,69 @@
+package ratelimit
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidLimit is returned by ParseLimit for a malformed limit.
+var ErrInvalidLimit = errors.New("invalid rate limit")
+
+// Budget names a group of routes sharing the requests a client may make.
+type Budget string
+
+// Budgets of the API: calculating orders and changing pack sizes or API keys.
+const (
+	BudgetCalculate Budget = "calculate"
+	BudgetMutate    Budget = "mutate"
+)
+
+// Limit allows Requests requests per Period to a client, in bursts of up to Requests. The
+// zero Limit allows any number of requests.
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// ParseLimit parses a limit written as requests per period, such as "300/1m" or "10/1s".
+// An empty string and "none" mean no limit.
+func ParseLimit(s string) (Limit, error) {
+	s = strings.TrimSpace(s)
+	if s == "" || s == "none" {
+		return Limit{}, nil
+	}
+
+	requests, period, ok := strings.Cut(s, "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("%w %q: expected requests/period", ErrInvalidLimit, s)
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(requests))
+	if err != nil || n <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: requests must be a positive integer", ErrInvalidLimit, s)
+	}
+	d, err := time.ParseDuration(strings.TrimSpace(period))
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("%w %q: period must be a positive duration", ErrInvalidLimit, s)
+	}
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// Unlimited reports whether l allows any number of requests.
+func (l Limit) Unlimited() bool {
+	return l.Requests <= 0 || l.Period <= 0
+}
+
+// rate returns the requests l gives back per second.
+func (l Limit) rate() float64 {
+	return float64(l.Requests) / l.Period.Seconds()
+}
+
+// String returns l in the form ParseLimit reads.
+func (l Limit) String() string {
+	if l.Unlimited() {
+		return "none"
+	}
+	return fmt.Sprintf("%d/%s", l.Requests, l.Period)
+}
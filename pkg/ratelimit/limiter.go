@@ -0,0 +1,213 @@
+package ratelimit
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+//go:generate mockgen -source=limiter.go -destination=../../internal/handler/mocks/mock_limiter.go -package=mocks
+
+// bucketPrefix starts the keys of the buckets a Limiter persists.
+const bucketPrefix = "ratelimit_"
+
+// sweepEvery is how many calls to Allow go between two sweeps of the buckets back to full,
+// which are dropped since a missing bucket is a full one.
+const sweepEvery = 1024
+
+// LimiterInterface decides whether a client may make cost more requests of a budget.
+type LimiterInterface interface {
+	Allow(budget Budget, client string, cost int) (Decision, error)
+}
+
+// Decision is the outcome of a call to Allow, with what the client is told about its
+// budget.
+type Decision struct {
+	Allowed bool
+	// Limit is the limit of the budget. Unlimited budgets allow every request and have
+	// no other fields set.
+	Limit Limit
+	// Remaining is how many more requests the client may make right away.
+	Remaining int
+	// Reset is how long until the budget of the client is full again.
+	Reset time.Duration
+	// RetryAfter is how long until the client may make a request, when it may not now.
+	RetryAfter time.Duration
+}
+
+// Option configures a Limiter at construction time.
+type Option func(*Limiter)
+
+// WithStore keeps the buckets in db as well as in memory, so that budgets are not reset
+// by a restart.
+func WithStore(db *leveldb.DB) Option {
+	return func(l *Limiter) {
+		l.db = db
+	}
+}
+
+// Limiter is a token bucket rate limiter with one bucket per budget and client. A bucket
+// holds up to the requests of the limit of its budget, and is refilled continuously over
+// its period.
+type Limiter struct {
+	limits map[Budget]Limit
+	db     *leveldb.DB
+	now    func() time.Time
+
+	mu      sync.Mutex
+	buckets map[string]*bucket
+	calls   int
+}
+
+// bucket is the state of a token bucket, as kept in memory and stored.
+type bucket struct {
+	Tokens  float64   `json:"tokens"`
+	Updated time.Time `json:"updated"`
+}
+
+// NewLimiter returns a Limiter enforcing limits. Budgets without a limit are unlimited.
+func NewLimiter(limits map[Budget]Limit, options ...Option) *Limiter {
+	l := &Limiter{
+		limits:  limits,
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+	for _, option := range options {
+		option(l)
+	}
+	return l
+}
+
+// Allow takes cost requests from the bucket of client for budget, if it holds that many,
+// and returns the resulting Decision. A cost above the limit of the budget is never
+// allowed.
+func (l *Limiter) Allow(budget Budget, client string, cost int) (Decision, error) {
+	limit := l.limits[budget]
+	if limit.Unlimited() {
+		return Decision{Allowed: true}, nil
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if l.calls++; l.calls%sweepEvery == 0 {
+		if err := l.sweep(); err != nil {
+			return Decision{}, err
+		}
+	}
+
+	key := bucketPrefix + string(budget) + "_" + client
+	b, err := l.bucket(key, limit)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	now := l.now()
+	rate := limit.rate()
+	capacity := float64(limit.Requests)
+	b.Tokens = math.Min(capacity, b.Tokens+now.Sub(b.Updated).Seconds()*rate)
+	b.Updated = now
+
+	decision := Decision{Limit: limit}
+	if b.Tokens >= float64(cost) {
+		b.Tokens -= float64(cost)
+		decision.Allowed = true
+	} else {
+		decision.RetryAfter = seconds((float64(cost) - b.Tokens) / rate)
+	}
+	decision.Remaining = int(b.Tokens)
+	decision.Reset = seconds((capacity - b.Tokens) / rate)
+
+	if err := l.store(key, b); err != nil {
+		return Decision{}, err
+	}
+	return decision, nil
+}
+
+// bucket returns the bucket at key, read from the store on first use. A new bucket is
+// full.
+func (l *Limiter) bucket(key string, limit Limit) (*bucket, error) {
+	if b, ok := l.buckets[key]; ok {
+		return b, nil
+	}
+
+	b := &bucket{Tokens: float64(limit.Requests), Updated: l.now()}
+	if l.db != nil {
+		value, err := l.db.Get([]byte(key), nil)
+		if err != nil && err != leveldb.ErrNotFound {
+			return nil, err
+		}
+		if err == nil {
+			if err := json.Unmarshal(value, b); err != nil {
+				return nil, fmt.Errorf("invalid record for %s: %v", key, err)
+			}
+		}
+	}
+	l.buckets[key] = b
+	return b, nil
+}
+
+// store writes b at key, when the limiter has a store.
+func (l *Limiter) store(key string, b *bucket) error {
+	if l.db == nil {
+		return nil
+	}
+	value, err := json.Marshal(b)
+	if err != nil {
+		return err
+	}
+	return l.db.Put([]byte(key), value, nil)
+}
+
+// sweep drops the buckets that are full again, from memory and from the store.
+func (l *Limiter) sweep() error {
+	now := l.now()
+	full := func(key string, b bucket) bool {
+		limit := l.limits[l.budgetOf(key)]
+		return limit.Unlimited() || b.Tokens+now.Sub(b.Updated).Seconds()*limit.rate() >= float64(limit.Requests)
+	}
+
+	for key, b := range l.buckets {
+		if full(key, *b) {
+			delete(l.buckets, key)
+		}
+	}
+	if l.db == nil {
+		return nil
+	}
+
+	batch := new(leveldb.Batch)
+	iter := l.db.NewIterator(util.BytesPrefix([]byte(bucketPrefix)), nil)
+	for iter.Next() {
+		var b bucket
+		if err := json.Unmarshal(iter.Value(), &b); err != nil || full(string(iter.Key()), b) {
+			batch.Delete(append([]byte(nil), iter.Key()...))
+		}
+	}
+	iter.Release()
+	if err := iter.Error(); err != nil {
+		return err
+	}
+	return l.db.Write(batch, nil)
+}
+
+// budgetOf returns the budget of the bucket at key, or "" for one of no known budget.
+func (l *Limiter) budgetOf(key string) Budget {
+	for budget := range l.limits {
+		if strings.HasPrefix(key, bucketPrefix+string(budget)+"_") {
+			return budget
+		}
+	}
+	return ""
+}
+
+// seconds converts a number of seconds to a time.Duration.
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,132 @@
+package ratelimit
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+)
+
+// newClock returns a clock starting at a fixed time, and a function moving it forward.
+func newClock() (func() time.Time, func(time.Duration)) {
+	now := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	return func() time.Time { return now }, func(d time.Duration) { now = now.Add(d) }
+}
+
+func TestParseLimit(t *testing.T) {
+	limit, err := ParseLimit("300/1m")
+	assert.NoError(t, err)
+	assert.Equal(t, Limit{Requests: 300, Period: time.Minute}, limit)
+	assert.Equal(t, "300/1m0s", limit.String())
+
+	for _, s := range []string{"", "none"} {
+		limit, err := ParseLimit(s)
+		assert.NoError(t, err)
+		assert.True(t, limit.Unlimited())
+	}
+
+	for _, s := range []string{"300", "x/1m", "0/1m", "10/x", "10/-1s"} {
+		_, err := ParseLimit(s)
+		assert.ErrorIs(t, err, ErrInvalidLimit, s)
+	}
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	now, advance := newClock()
+	l := NewLimiter(map[Budget]Limit{BudgetCalculate: {Requests: 2, Period: 2 * time.Second}})
+	l.now = now
+
+	d, err := l.Allow(BudgetCalculate, "a", 1)
+	require.NoError(t, err)
+	assert.Equal(t, Decision{Allowed: true, Limit: Limit{Requests: 2, Period: 2 * time.Second}, Remaining: 1, Reset: time.Second}, d)
+
+	d, _ = l.Allow(BudgetCalculate, "a", 1)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+	assert.Equal(t, 2*time.Second, d.Reset)
+
+	d, _ = l.Allow(BudgetCalculate, "a", 1)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, time.Second, d.RetryAfter)
+
+	// Other clients and budgets have their own buckets.
+	d, _ = l.Allow(BudgetCalculate, "b", 1)
+	assert.True(t, d.Allowed)
+	d, _ = l.Allow(BudgetMutate, "a", 1)
+	assert.Equal(t, Decision{Allowed: true}, d)
+
+	// The bucket is refilled over the period, up to the limit.
+	advance(500 * time.Millisecond)
+	d, _ = l.Allow(BudgetCalculate, "a", 1)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 500*time.Millisecond, d.RetryAfter)
+
+	advance(time.Hour)
+	for i := 0; i < 2; i++ {
+		d, _ = l.Allow(BudgetCalculate, "a", 1)
+		assert.True(t, d.Allowed)
+	}
+	d, _ = l.Allow(BudgetCalculate, "a", 1)
+	assert.False(t, d.Allowed)
+}
+
+func TestLimiter_AllowCost(t *testing.T) {
+	now, advance := newClock()
+	l := NewLimiter(map[Budget]Limit{BudgetCalculate: {Requests: 4, Period: 4 * time.Second}})
+	l.now = now
+
+	d, err := l.Allow(BudgetCalculate, "a", 3)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+	assert.Equal(t, 3*time.Second, d.Reset)
+
+	// A cost above what is left is refused and takes nothing.
+	d, _ = l.Allow(BudgetCalculate, "a", 2)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 1, d.Remaining)
+	assert.Equal(t, time.Second, d.RetryAfter)
+
+	advance(time.Second)
+	d, _ = l.Allow(BudgetCalculate, "a", 2)
+	assert.True(t, d.Allowed)
+	assert.Equal(t, 0, d.Remaining)
+
+	// A cost above the limit is never allowed.
+	advance(time.Hour)
+	d, _ = l.Allow(BudgetCalculate, "a", 5)
+	assert.False(t, d.Allowed)
+	assert.Equal(t, 4, d.Remaining)
+}
+
+func TestLimiter_Store(t *testing.T) {
+	db, err := leveldb.OpenFile(t.TempDir(), nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	limits := map[Budget]Limit{BudgetMutate: {Requests: 1, Period: time.Minute}}
+	now, advance := newClock()
+	l := NewLimiter(limits, WithStore(db))
+	l.now = now
+
+	d, err := l.Allow(BudgetMutate, "a", 1)
+	require.NoError(t, err)
+	assert.True(t, d.Allowed)
+
+	// A limiter started again over the same store keeps the budgets.
+	restarted := NewLimiter(limits, WithStore(db))
+	restarted.now = now
+	d, err = restarted.Allow(BudgetMutate, "a", 1)
+	require.NoError(t, err)
+	assert.False(t, d.Allowed)
+
+	// Full buckets are swept from memory and from the store.
+	advance(time.Minute)
+	require.NoError(t, restarted.sweep())
+	assert.Empty(t, restarted.buckets)
+	has, err := db.Has([]byte(bucketPrefix+"mutate_a"), nil)
+	require.NoError(t, err)
+	assert.False(t, has)
+}
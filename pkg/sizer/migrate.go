@@ -0,0 +1,227 @@
+package sizer
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// schemaVersionKey holds the version of the key layout as a decimal number. Databases
+// written before versioning have no such key and are at version 0.
+var schemaVersionKey = []byte("schema_version")
+
+// ErrSchemaTooNew is returned by NewSizer for a database written by a release with a
+// newer key layout than this one understands.
+var ErrSchemaTooNew = errors.New("database schema is newer than supported")
+
+// migration upgrades the database by one schema version. apply reads the database and
+// queues its changes in batch, which is written together with the new version so that a
+// migration is never left half applied.
+type migration struct {
+	description string
+	apply       func(db *leveldb.DB, batch *leveldb.Batch) error
+}
+
+// migrations holds every upgrade in order: migrations[i] moves a database from version i
+// to version i+1. New layouts are introduced by appending to it, never by editing an
+// entry that has shipped.
+var migrations = []migration{
+	{description: "store pack sizes as JSON records", apply: migrateRecords},
+	{description: "move the default catalog under its catalog prefix", apply: migrateDefaultCatalog},
+	{description: "record the pack sizes of every catalog as its first version", apply: migrateVersions},
+	{description: "replace the populated flag with the default catalog history", apply: migratePopulatedFlag},
+}
+
+// SchemaVersion is the version of the key layout written by this package.
+func SchemaVersion() int {
+	return len(migrations)
+}
+
+// migrate brings db to SchemaVersion, applying the migrations it has not seen yet. It
+// returns ErrSchemaTooNew, leaving db untouched, when db is already past it.
+func migrate(db *leveldb.DB, l logger.Logger) error {
+	version, err := schemaVersion(db)
+	if err != nil {
+		return err
+	}
+	if version > SchemaVersion() {
+		return fmt.Errorf("%w: found version %d, supported up to %d", ErrSchemaTooNew, version, SchemaVersion())
+	}
+
+	for ; version < SchemaVersion(); version++ {
+		m := migrations[version]
+		batch := new(leveldb.Batch)
+		if err := m.apply(db, batch); err != nil {
+			return fmt.Errorf("failed to migrate to schema version %d: %v", version+1, err)
+		}
+		batch.Put(schemaVersionKey, []byte(strconv.Itoa(version+1)))
+		if err := db.Write(batch, nil); err != nil {
+			return fmt.Errorf("failed to migrate to schema version %d: %v", version+1, err)
+		}
+		l.Info(fmt.Sprintf("migrated database to schema version %d: %s", version+1, m.description))
+	}
+
+	return nil
+}
+
+// schemaVersion returns the schema version recorded in db.
+func schemaVersion(db *leveldb.DB) (int, error) {
+	data, err := db.Get(schemaVersionKey, nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	version, err := strconv.Atoi(string(data))
+	if err != nil || version < 0 {
+		return 0, fmt.Errorf("invalid schema version %q", data)
+	}
+	return version, nil
+}
+
+// migrateRecords rewrites every size holding its decimal value as a JSON record, folding
+// in the price and stock kept under their own keys, in every catalog.
+func migrateRecords(db *leveldb.DB, batch *leveldb.Batch) error {
+	iter := db.NewIterator(nil, nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		prefix, name := splitKey(iter.Key())
+		if !strings.HasPrefix(name, sizePrefix) || isRecord(iter.Value()) {
+			continue
+		}
+		size, err := strconv.Atoi(name[len(sizePrefix):])
+		if err != nil {
+			continue
+		}
+
+		pack := NewPack(size)
+		priceKey := []byte(prefix + pricePrefix + strconv.Itoa(size))
+		if data, err := db.Get(priceKey, nil); err == nil {
+			if pack.Price, err = strconv.ParseFloat(string(data), 64); err != nil {
+				return fmt.Errorf("invalid price for size %d: %v", size, err)
+			}
+		} else if err != leveldb.ErrNotFound {
+			return err
+		}
+
+		stockKey := []byte(prefix + stockPrefix + strconv.Itoa(size))
+		if data, err := db.Get(stockKey, nil); err == nil {
+			stock, err := strconv.Atoi(string(data))
+			if err != nil {
+				return fmt.Errorf("invalid stock for size %d: %v", size, err)
+			}
+			pack.Stock = &stock
+		} else if err != leveldb.ErrNotFound {
+			return err
+		}
+
+		value, err := encodePack(pack)
+		if err != nil {
+			return err
+		}
+		batch.Put(append([]byte(nil), iter.Key()...), value)
+		batch.Delete(priceKey)
+		batch.Delete(stockKey)
+	}
+
+	return iter.Error()
+}
+
+// migrateDefaultCatalog moves the sizes of the default catalog from the unprefixed keys
+// written before catalogs existed to the prefix every other catalog uses.
+func migrateDefaultCatalog(db *leveldb.DB, batch *leveldb.Batch) error {
+	iter := db.NewIterator(util.BytesPrefix([]byte(sizePrefix)), nil)
+	defer iter.Release()
+
+	for iter.Next() {
+		size, err := strconv.Atoi(string(iter.Key()[len(sizePrefix):]))
+		if err != nil {
+			continue
+		}
+		batch.Put(sizeKey(DefaultCatalog, size), append([]byte(nil), iter.Value()...))
+		batch.Delete(append([]byte(nil), iter.Key()...))
+	}
+
+	return iter.Error()
+}
+
+// migrateVersions records the pack sizes every catalog holds as its first version, the
+// start of its history.
+func migrateVersions(db *leveldb.DB, batch *leveldb.Batch) error {
+	iter := db.NewIterator(util.BytesPrefix([]byte(catalogPrefix)), nil)
+	defer iter.Release()
+
+	packs := make(map[string][]Pack)
+	var catalogs []string
+	for iter.Next() {
+		prefix, name := splitKey(iter.Key())
+		if !strings.HasPrefix(name, sizePrefix) {
+			continue
+		}
+		size, err := strconv.Atoi(name[len(sizePrefix):])
+		if err != nil {
+			continue
+		}
+		pack, err := decodePack(size, iter.Value())
+		if err != nil {
+			return err
+		}
+
+		catalog := strings.TrimSuffix(prefix[len(catalogPrefix):], "/")
+		if _, ok := packs[catalog]; !ok {
+			catalogs = append(catalogs, catalog)
+		}
+		packs[catalog] = append(packs[catalog], pack)
+	}
+	if err := iter.Error(); err != nil {
+		return err
+	}
+
+	for _, catalog := range catalogs {
+		ps := packs[catalog]
+		sort.Slice(ps, func(i, j int) bool { return ps[i].Size > ps[j].Size })
+		v := Version{Number: 1, CreatedAt: time.Now().UTC(), Reason: "initial version", Packs: ps}
+		if err := putVersion(batch, catalog, v); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// populatedKey flagged, before schema version 4, that the default catalog had been
+// seeded.
+var populatedKey = []byte("packs")
+
+// migratePopulatedFlag drops the populated flag. A default catalog that was seeded but
+// holds no sizes any more has no history to tell so, and is given an empty first version
+// to keep it from being seeded again.
+func migratePopulatedFlag(db *leveldb.DB, batch *leveldb.Batch) error {
+	populated, err := db.Has(populatedKey, nil)
+	if err != nil || !populated {
+		return err
+	}
+	batch.Delete(populatedKey)
+
+	history, err := db.Has([]byte(keyPrefix(DefaultCatalog)+latestVersionKey), nil)
+	if err != nil || history {
+		return err
+	}
+	return putVersion(batch, DefaultCatalog, Version{Number: 1, CreatedAt: time.Now().UTC(), Reason: "initial version"})
+}
+
+// splitKey splits key into its catalog prefix, empty for unprefixed keys, and the name
+// that follows it.
+func splitKey(key []byte) (prefix, name string) {
+	i := bytes.LastIndexByte(key, '/')
+	return string(key[:i+1]), string(key[i+1:])
+}
@@ -0,0 +1,87 @@
+package sizer
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+)
+
+// Pack describes a pack size together with its optional unit price and the number of
+// packs on hand. A nil Stock means the size is not stock-limited. Only enabled sizes are
+// used to fulfil orders. Code, Name, Weight, Dimensions and Labels are informational and
+// use whatever units the catalog agrees on.
+type Pack struct {
+	Size       int               `json:"size" yaml:"size"`
+	Code       string            `json:"code,omitempty" yaml:"code,omitempty"`
+	Name       string            `json:"name,omitempty" yaml:"name,omitempty"`
+	Enabled    bool              `json:"enabled" yaml:"enabled"`
+	Price      float64           `json:"price,omitempty" yaml:"price,omitempty"`
+	Stock      *int              `json:"stock,omitempty" yaml:"stock,omitempty"`
+	Weight     float64           `json:"weight,omitempty" yaml:"weight,omitempty"`
+	Dimensions *Dimensions       `json:"dimensions,omitempty" yaml:"dimensions,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty" yaml:"labels,omitempty"`
+}
+
+// Dimensions is the outer size of a pack.
+type Dimensions struct {
+	Length float64 `json:"length" yaml:"length"`
+	Width  float64 `json:"width" yaml:"width"`
+	Height float64 `json:"height" yaml:"height"`
+}
+
+// FieldError reports a pack field holding a value that cannot be stored. Field is its
+// JSON name.
+type FieldError struct {
+	Field   string
+	Message string
+}
+
+func (e *FieldError) Error() string {
+	return e.Message
+}
+
+// Validate reports the first field of p that cannot be stored as a *FieldError.
+func (p Pack) Validate() error {
+	switch {
+	case p.Size <= 0:
+		return &FieldError{Field: "size", Message: "pack size must be greater than 0"}
+	case p.Price < 0:
+		return &FieldError{Field: "price", Message: "price must not be negative"}
+	case p.Stock != nil && *p.Stock < 0:
+		return &FieldError{Field: "stock", Message: "stock must not be negative"}
+	case p.Weight < 0:
+		return &FieldError{Field: "weight", Message: "weight must not be negative"}
+	case p.Dimensions != nil && (p.Dimensions.Length < 0 || p.Dimensions.Width < 0 || p.Dimensions.Height < 0):
+		return &FieldError{Field: "dimensions", Message: "dimensions must not be negative"}
+	}
+	return nil
+}
+
+// NewPack returns an enabled pack of size with no other attribute set.
+func NewPack(size int) Pack {
+	return Pack{Size: size, Enabled: true}
+}
+
+// encodePack returns the value stored under the size key of p.
+func encodePack(p Pack) ([]byte, error) {
+	return json.Marshal(p)
+}
+
+// isRecord reports whether value is a structured pack record rather than the decimal
+// size written before records existed.
+func isRecord(value []byte) bool {
+	return bytes.HasPrefix(bytes.TrimSpace(value), []byte("{"))
+}
+
+// decodePack parses a structured pack record stored for size. Records that predate a
+// field read it as its zero value, except Enabled which defaults to true.
+func decodePack(size int, value []byte) (Pack, error) {
+	p := NewPack(size)
+	if err := json.Unmarshal(value, &p); err != nil {
+		return Pack{}, fmt.Errorf("invalid record for size %d: %v", size, err)
+	}
+	if p.Size != size {
+		return Pack{}, fmt.Errorf("record for size %d holds size %d", size, p.Size)
+	}
+	return p, nil
+}
@@ -0,0 +1,99 @@
+package sizer
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+// seedReason is the reason recorded for the version created by seeding.
+const seedReason = "seed catalog"
+
+// DefaultSeed lists, in the form ParseSeed reads, the sizes the default catalog is seeded
+// with unless an Option says otherwise.
+const DefaultSeed = "250,500,1000,2000,5000"
+
+// Option configures a Sizer.
+type Option func(*Sizer)
+
+// WithSeed seeds an empty default catalog with packs instead of the default sizes.
+// Seeding with no packs skips it.
+func WithSeed(packs []Pack) Option {
+	return func(s *Sizer) {
+		s.seed = packs
+	}
+}
+
+// WithoutSeed leaves an empty default catalog empty.
+func WithoutSeed() Option {
+	return WithSeed(nil)
+}
+
+// ParseSeed parses a comma separated list of pack sizes, such as "250,500,1000", into
+// enabled packs of those sizes.
+func ParseSeed(v string) ([]Pack, error) {
+	var packs []Pack
+	seen := make(map[int]bool)
+	for _, field := range strings.Split(v, ",") {
+		size, err := strconv.Atoi(strings.TrimSpace(field))
+		if err != nil {
+			return nil, fmt.Errorf("invalid seed size %q", field)
+		}
+		pack := NewPack(size)
+		if err := pack.Validate(); err != nil {
+			return nil, err
+		}
+		if seen[size] {
+			return nil, fmt.Errorf("seed size %d given more than once", size)
+		}
+		seen[size] = true
+		packs = append(packs, pack)
+	}
+	return packs, nil
+}
+
+// ReadSeedFile reads the packs of the seed file at path, in the import Format named by
+// its extension.
+func ReadSeedFile(path string) ([]Pack, error) {
+	f, err := ParseFormat(strings.TrimPrefix(filepath.Ext(path), "."))
+	if err != nil {
+		return nil, fmt.Errorf("seed file %s: %w", path, err)
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	packs, err := ParseImport(data, f)
+	if err != nil {
+		return nil, fmt.Errorf("seed file %s: %w", path, err)
+	}
+	return packs, nil
+}
+
+// Populate seeds the default catalog with the configured packs when it has never held
+// any. Whether it did is told by its history rather than by its current sizes, so a
+// catalog emptied on purpose is never seeded again.
+func (s *Sizer) Populate() error {
+	latest, err := s.LatestVersion(DefaultCatalog)
+	if err != nil {
+		return fmt.Errorf("failed to check the catalog history: %v", err)
+	}
+	if latest > 0 {
+		return nil
+	}
+	if len(s.seed) == 0 {
+		s.logger.Info("seeding skipped, the default catalog starts empty")
+		return nil
+	}
+
+	err = s.change(DefaultCatalog, seedReason, func(packs map[int]Pack) error {
+		return replaceIn(packs, s.seed)
+	})
+	if err != nil {
+		return fmt.Errorf("failed to seed the default catalog: %v", err)
+	}
+	s.logger.Info(fmt.Sprintf("seeded the default catalog with %d pack sizes", len(s.seed)))
+	return nil
+}
@@ -0,0 +1,139 @@
+package sizer_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestNewSizer_WithSeed(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	seed := []sizer.Pack{sizer.NewPack(40), {Size: 80, Enabled: true, Price: 2}}
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel), sizer.WithSeed(seed))
+	require.NoError(t, err)
+
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []sizer.Pack{seed[1], seed[0]}, packs)
+
+	v, err := s.GetVersion(sizer.DefaultCatalog, 1)
+	assert.NoError(t, err)
+	assert.Equal(t, "seed catalog", v.Reason)
+
+	// A catalog emptied on purpose stays empty.
+	assert.NoError(t, s.ReplaceSizes(sizer.DefaultCatalog, nil))
+	require.NoError(t, s.Close())
+
+	s, err = sizer.NewSizer(dir, logger.New(zapcore.DebugLevel), sizer.WithSeed(seed))
+	require.NoError(t, err)
+	defer s.Close()
+
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Empty(t, sizes)
+}
+
+func TestNewSizer_WithoutSeed(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel), sizer.WithoutSeed())
+	require.NoError(t, err)
+
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Empty(t, sizes)
+	require.NoError(t, s.Close())
+
+	// Seeding is skipped without a trace, so a later start can still seed.
+	s, err = sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	sizes, err = s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5000, 2000, 1000, 500, 250}, sizes)
+}
+
+func TestNewSizer_InvalidSeed(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	_, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel), sizer.WithSeed([]sizer.Pack{sizer.NewPack(-1)}))
+	assert.Error(t, err)
+
+	// The database was closed and can be opened again.
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	assert.NoError(t, s.Close())
+}
+
+func TestNewSizer_PopulatedFlag(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	// A database seeded before schema version 4 whose default catalog was emptied.
+	db, err := leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("schema_version"), []byte("3"), nil))
+	require.NoError(t, db.Put([]byte("packs"), []byte("populated"), nil))
+	require.NoError(t, db.Close())
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Empty(t, sizes)
+
+	latest, err := s.LatestVersion(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, 1, latest)
+}
+
+func TestParseSeed(t *testing.T) {
+	packs, err := sizer.ParseSeed("250, 500,1000")
+	assert.NoError(t, err)
+	assert.Equal(t, []sizer.Pack{sizer.NewPack(250), sizer.NewPack(500), sizer.NewPack(1000)}, packs)
+
+	packs, err = sizer.ParseSeed(sizer.DefaultSeed)
+	assert.NoError(t, err)
+	assert.Len(t, packs, 5)
+
+	for _, v := range []string{"", "250,x", "250,0", "250,250"} {
+		_, err := sizer.ParseSeed(v)
+		assert.Error(t, err, v)
+	}
+}
+
+func TestReadSeedFile(t *testing.T) {
+	dir := t.TempDir()
+
+	path := filepath.Join(dir, "seed.csv")
+	require.NoError(t, os.WriteFile(path, []byte("size,price\n300,1.5\n600,\n"), 0o600))
+	packs, err := sizer.ReadSeedFile(path)
+	assert.NoError(t, err)
+	assert.Equal(t, []sizer.Pack{{Size: 300, Enabled: true, Price: 1.5}, sizer.NewPack(600)}, packs)
+
+	path = filepath.Join(dir, "seed.yaml")
+	require.NoError(t, os.WriteFile(path, []byte("- size: 0\n"), 0o600))
+	_, err = sizer.ReadSeedFile(path)
+	var importErr *sizer.ImportError
+	assert.ErrorAs(t, err, &importErr)
+
+	_, err = sizer.ReadSeedFile(filepath.Join(dir, "seed.xml"))
+	assert.ErrorIs(t, err, sizer.ErrUnknownFormat)
+
+	_, err = sizer.ReadSeedFile(filepath.Join(dir, "missing.json"))
+	assert.Error(t, err)
+}
@@ -1,33 +1,79 @@
 package sizer
 
 import (
+	"bytes"
 	"errors"
 	"fmt"
 	"sort"
 	"strconv"
-	"strings"
+	"sync"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/iterator"
 	"github.com/syndtr/goleveldb/leveldb/opt"
+	"github.com/syndtr/goleveldb/leveldb/util"
 )
 
-// SizerInterface defines the methods that any Sizer implementation must provide.
+// Key prefixes of the records stored for each pack size. Prices and stock only had their
+// own keys before schema version 1.
+const (
+	sizePrefix    = "size_"
+	pricePrefix   = "price_"
+	stockPrefix   = "stock_"
+	catalogPrefix = "catalog_"
+)
+
+// DefaultCatalog is the catalog served by the routes that do not name one.
+const DefaultCatalog = "default"
+
+// maxCatalogLength is the longest accepted catalog ID.
+const maxCatalogLength = 64
+
+// ErrSizeNotFound is returned when a pack size does not exist in a catalog.
+var ErrSizeNotFound = errors.New("pack size not found")
+
+// ErrInvalidCatalog is returned for a catalog ID that is empty, too long or holds
+// anything other than ASCII letters, digits, '-' and '_'.
+var ErrInvalidCatalog = errors.New("invalid catalog id")
+
+// SizerInterface defines the methods that any Sizer implementation must provide. Every
+// catalog holds an independent set of pack sizes, and every change to it is recorded as a
+// new catalog Version.
 type SizerInterface interface {
-	GetAllSizes() ([]int, error)
-	AddSize(size int) error
-	RemoveSize(size int) error
+	GetAllSizes(catalog string) ([]int, error)
+	GetAllPacks(catalog string) ([]Pack, error)
+	GetPack(catalog string, size int) (Pack, error)
+	AddSize(catalog string, size int) error
+	AddPack(catalog string, pack Pack) error
+	UpdatePack(catalog string, size int, update func(*Pack) error) error
+	SetEnabled(catalog string, size int, enabled bool) error
+	ReplaceSizes(catalog string, packs []Pack) error
+	MergePacks(catalog string, packs []Pack) error
+	RemoveSize(catalog string, size int) error
+	GetVersions(catalog string) ([]Version, error)
+	GetVersion(catalog string, number int) (Version, error)
+	LatestVersion(catalog string) (int, error)
+	CurrentVersion(catalog string) (Version, error)
+	RestoreVersion(catalog string, number int) error
 	Close() error
 }
 
 // Sizer is responsible for interacting with pack sizes stored in a LevelDB database.
+// Each size is stored as a JSON Pack record under its size key, in the layout of
+// SchemaVersion. mu serialises writes so that UpdatePack can read and modify a record
+// atomically. seed holds the packs an empty default catalog is seeded with.
 type Sizer struct {
 	db     *leveldb.DB
 	logger logger.Logger
+	mu     sync.Mutex
+	seed   []Pack
 }
 
-// NewSizer opens or creates a LevelDB instance and populates it with default sizes if needed.
-func NewSizer(path string, l logger.Logger) (*Sizer, error) {
+// NewSizer opens or creates a LevelDB instance, migrates it to SchemaVersion and seeds
+// the default catalog if it has never held any sizes. It returns ErrSchemaTooNew for a
+// database written by a newer release.
+func NewSizer(path string, l logger.Logger, options ...Option) (*Sizer, error) {
 	db, err := leveldb.OpenFile(path, &opt.Options{
 		ErrorIfMissing: false,
 	})
@@ -35,40 +81,33 @@ func NewSizer(path string, l logger.Logger) (*Sizer, error) {
 		return nil, err
 	}
 
+	if err := migrate(db, l); err != nil {
+		db.Close()
+		return nil, err
+	}
+
 	sizer := &Sizer{
 		db:     db,
 		logger: l,
 	}
+	// DefaultSeed is valid, as TestParseSeed checks.
+	sizer.seed, _ = ParseSeed(DefaultSeed)
+	for _, option := range options {
+		option(sizer)
+	}
 
 	if err := sizer.Populate(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return sizer, nil
 }
 
-// Populate fills the database with default pack sizes if it is empty or missing required data.
-func (s *Sizer) Populate() error {
-	data, err := s.db.Get([]byte("packs"), nil)
-	if err == leveldb.ErrNotFound || len(data) == 0 {
-		s.logger.Info("Table not found, creating and populating table...")
-
-		defaultSizes := []int{250, 500, 1000, 2000, 5000}
-
-		for _, size := range defaultSizes {
-			if err := s.db.Put([]byte(fmt.Sprintf("size_%d", size)), []byte(fmt.Sprintf("%d", size)), nil); err != nil {
-				return fmt.Errorf("failed to insert default size %d: %v", size, err)
-			}
-		}
-
-		if err := s.db.Put([]byte("packs"), []byte("populated"), nil); err != nil {
-			return fmt.Errorf("failed to set populated flag: %v", err)
-		}
-	} else if err != nil {
-		return fmt.Errorf("failed to check if packs exist: %v", err)
-	}
-
-	return nil
+// DB returns the database the sizer stores its records in, for packages that keep their
+// own records alongside them under key prefixes of their own.
+func (s *Sizer) DB() *leveldb.DB {
+	return s.db
 }
 
 // Close closes the DB connection
@@ -76,22 +115,23 @@ func (s *Sizer) Close() error {
 	return s.db.Close()
 }
 
-// GetAllSizes returns all sizes from LevelDB sorted in descending order
-func (s *Sizer) GetAllSizes() ([]int, error) {
-	iter := s.db.NewIterator(nil, nil)
+// GetAllSizes returns all sizes of catalog from LevelDB sorted in descending order
+func (s *Sizer) GetAllSizes(catalog string) ([]int, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return nil, err
+	}
+
+	prefix := keyPrefix(catalog) + sizePrefix
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
 	defer iter.Release()
 
 	var sizes []int
 	for iter.Next() {
-		key := string(iter.Key())
-		if strings.HasPrefix(key, "size_") {
-			sizeStr := key[len("size_"):]
-			size, err := strconv.Atoi(sizeStr)
-			if err != nil {
-				continue
-			}
-			sizes = append(sizes, size)
+		size, err := strconv.Atoi(string(iter.Key()[len(prefix):]))
+		if err != nil {
+			continue
 		}
+		sizes = append(sizes, size)
 	}
 
 	sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
@@ -99,21 +139,261 @@ func (s *Sizer) GetAllSizes() ([]int, error) {
 	return sizes, iter.Error()
 }
 
-// AddSize adds a new pack size to the database
-func (s *Sizer) AddSize(size int) error {
-	key := fmt.Sprintf("size_%d", size)
-	return s.db.Put([]byte(key), []byte(fmt.Sprintf("%d", size)), nil)
+// GetAllPacks returns all pack records of catalog, sorted by size in descending order.
+// The records are read from a single snapshot of the database, so a concurrent change is
+// either fully seen or not at all.
+func (s *Sizer) GetAllPacks(catalog string) ([]Pack, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return nil, err
+	}
+
+	snap, err := s.db.GetSnapshot()
+	if err != nil {
+		return nil, err
+	}
+	defer snap.Release()
+
+	return packsIn(snap, catalog)
+}
+
+// reader is the read side of a LevelDB database, implemented by the database and its
+// snapshots.
+type reader interface {
+	Get(key []byte, ro *opt.ReadOptions) ([]byte, error)
+	NewIterator(slice *util.Range, ro *opt.ReadOptions) iterator.Iterator
+}
+
+// packsIn returns all pack records of catalog in r, sorted by size in descending order.
+func packsIn(r reader, catalog string) ([]Pack, error) {
+	prefix := keyPrefix(catalog) + sizePrefix
+	iter := r.NewIterator(util.BytesPrefix([]byte(prefix)), nil)
+	defer iter.Release()
+
+	packs := make([]Pack, 0)
+	for iter.Next() {
+		size, err := strconv.Atoi(string(iter.Key()[len(prefix):]))
+		if err != nil {
+			continue
+		}
+		pack, err := decodePack(size, iter.Value())
+		if err != nil {
+			return nil, err
+		}
+		packs = append(packs, pack)
+	}
+	if err := iter.Error(); err != nil {
+		return nil, err
+	}
+
+	sort.Slice(packs, func(i, j int) bool { return packs[i].Size > packs[j].Size })
+	return packs, nil
+}
+
+// GetPack returns the record of size in catalog, or ErrSizeNotFound
+func (s *Sizer) GetPack(catalog string, size int) (Pack, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return Pack{}, err
+	}
+
+	value, err := s.db.Get(sizeKey(catalog, size), nil)
+	if err == leveldb.ErrNotFound {
+		return Pack{}, ErrSizeNotFound
+	}
+	if err != nil {
+		return Pack{}, err
+	}
+	return decodePack(size, value)
+}
+
+// AddSize adds a new enabled pack size to catalog in the database. An existing size keeps
+// its record.
+func (s *Sizer) AddSize(catalog string, size int) error {
+	return s.change(catalog, fmt.Sprintf("add size %d", size), func(packs map[int]Pack) error {
+		if _, ok := packs[size]; !ok {
+			packs[size] = NewPack(size)
+		}
+		return nil
+	})
+}
+
+// AddPack adds or replaces the record of a pack size of catalog.
+func (s *Sizer) AddPack(catalog string, pack Pack) error {
+	return s.change(catalog, fmt.Sprintf("set size %d", pack.Size), func(packs map[int]Pack) error {
+		packs[pack.Size] = pack
+		return nil
+	})
+}
+
+// UpdatePack applies update to the record of size in catalog and stores the result, or
+// returns ErrSizeNotFound. Nothing is stored when update fails, changes the size or
+// leaves an invalid record.
+func (s *Sizer) UpdatePack(catalog string, size int, update func(*Pack) error) error {
+	return s.change(catalog, fmt.Sprintf("update size %d", size), func(packs map[int]Pack) error {
+		return updateIn(packs, size, update)
+	})
+}
+
+// SetEnabled enables or disables size in catalog, keeping the rest of its record. It
+// returns ErrSizeNotFound when the size does not exist.
+func (s *Sizer) SetEnabled(catalog string, size int, enabled bool) error {
+	reason := fmt.Sprintf("disable size %d", size)
+	if enabled {
+		reason = fmt.Sprintf("enable size %d", size)
+	}
+	return s.change(catalog, reason, func(packs map[int]Pack) error {
+		return updateIn(packs, size, func(p *Pack) error {
+			p.Enabled = enabled
+			return nil
+		})
+	})
+}
+
+// ReplaceSizes makes packs the complete set of pack sizes of catalog, removing every size
+// not in it, in a single batch write. Nothing is stored when a pack is invalid or a size
+// is given twice.
+func (s *Sizer) ReplaceSizes(catalog string, packs []Pack) error {
+	return s.change(catalog, "replace all sizes", func(current map[int]Pack) error {
+		return replaceIn(current, packs)
+	})
+}
+
+// MergePacks adds or replaces the records of packs in catalog, keeping the sizes packs
+// leaves out, in a single batch write. Nothing is stored when a pack is invalid or a size
+// is given twice.
+func (s *Sizer) MergePacks(catalog string, packs []Pack) error {
+	return s.change(catalog, fmt.Sprintf("merge %d sizes", len(packs)), func(current map[int]Pack) error {
+		merged := make(map[int]bool, len(packs))
+		for _, pack := range packs {
+			if merged[pack.Size] {
+				return &FieldError{Field: "size", Message: fmt.Sprintf("pack size %d given more than once", pack.Size)}
+			}
+			merged[pack.Size] = true
+			current[pack.Size] = pack
+		}
+		return nil
+	})
+}
+
+// RemoveSize deletes a pack size of catalog from the database
+func (s *Sizer) RemoveSize(catalog string, size int) error {
+	return s.change(catalog, fmt.Sprintf("remove size %d", size), func(packs map[int]Pack) error {
+		if _, ok := packs[size]; !ok {
+			return ErrSizeNotFound
+		}
+		delete(packs, size)
+		return nil
+	})
 }
 
-// RemoveSize deletes a pack size from the database
-func (s *Sizer) RemoveSize(size int) error {
-	key := fmt.Sprintf("size_%d", size)
-	exists, err := s.db.Has([]byte(key), nil)
+// change applies edit to the pack records of catalog, keyed by size, and stores the
+// result together with a new catalog version described by reason in a single batch
+// write. Nothing is stored when edit fails or leaves an invalid record, and no version is
+// created when the records end up as they were.
+func (s *Sizer) change(catalog, reason string, edit func(packs map[int]Pack) error) error {
+	if err := CheckCatalog(catalog); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	current, err := s.GetAllPacks(catalog)
 	if err != nil {
 		return err
 	}
-	if !exists {
-		return errors.New("pack size not found")
+	before := make(map[int][]byte, len(current))
+	packs := make(map[int]Pack, len(current))
+	for _, pack := range current {
+		if before[pack.Size], err = encodePack(pack); err != nil {
+			return err
+		}
+		packs[pack.Size] = pack
 	}
-	return s.db.Delete([]byte(key), nil)
+
+	if err := edit(packs); err != nil {
+		return err
+	}
+
+	batch := new(leveldb.Batch)
+	for size := range before {
+		if _, ok := packs[size]; !ok {
+			batch.Delete(sizeKey(catalog, size))
+		}
+	}
+	for size, pack := range packs {
+		if err := pack.Validate(); err != nil {
+			return err
+		}
+		value, err := encodePack(pack)
+		if err != nil {
+			return err
+		}
+		if old, ok := before[size]; !ok || !bytes.Equal(old, value) {
+			batch.Put(sizeKey(catalog, size), value)
+		}
+	}
+	if batch.Len() == 0 {
+		return nil
+	}
+
+	if err := s.addVersion(batch, catalog, reason, packs); err != nil {
+		return err
+	}
+	return s.db.Write(batch, nil)
+}
+
+// updateIn applies update to the record of size in packs, or returns ErrSizeNotFound.
+func updateIn(packs map[int]Pack, size int, update func(*Pack) error) error {
+	pack, ok := packs[size]
+	if !ok {
+		return ErrSizeNotFound
+	}
+	if err := update(&pack); err != nil {
+		return err
+	}
+	if pack.Size != size {
+		return &FieldError{Field: "size", Message: "pack size cannot be changed"}
+	}
+	packs[size] = pack
+	return nil
+}
+
+// replaceIn makes replacement the only records of packs.
+func replaceIn(packs map[int]Pack, replacement []Pack) error {
+	for size := range packs {
+		delete(packs, size)
+	}
+	for _, pack := range replacement {
+		if _, ok := packs[pack.Size]; ok {
+			return &FieldError{Field: "size", Message: fmt.Sprintf("pack size %d given more than once", pack.Size)}
+		}
+		packs[pack.Size] = pack
+	}
+	return nil
+}
+
+// CheckCatalog returns ErrInvalidCatalog unless id is a valid catalog ID.
+func CheckCatalog(id string) error {
+	if id == "" || len(id) > maxCatalogLength {
+		return ErrInvalidCatalog
+	}
+	for _, c := range id {
+		switch {
+		case c >= 'a' && c <= 'z', c >= 'A' && c <= 'Z', c >= '0' && c <= '9', c == '-', c == '_':
+		default:
+			return ErrInvalidCatalog
+		}
+	}
+	return nil
+}
+
+// keyPrefix returns the prefix of every key of catalog. Catalog IDs cannot hold '/', so
+// no prefix is the start of another.
+func keyPrefix(catalog string) string {
+	return catalogPrefix + catalog + "/"
+}
+
+// sizeKey returns the key marking size as an available pack size of catalog.
+func sizeKey(catalog string, size int) []byte {
+	return []byte(keyPrefix(catalog) + sizePrefix + strconv.Itoa(size))
 }
@@ -2,11 +2,15 @@ package sizer_test
 
 import (
 	"os"
+	"strconv"
+	"strings"
 	"testing"
 
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
 	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
 	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/syndtr/goleveldb/leveldb"
 	"go.uber.org/zap/zapcore"
 )
 
@@ -25,7 +29,7 @@ func TestNewSizer_PopulatesDefaults(t *testing.T) {
 	assert.NoError(t, err)
 	defer s.Close()
 
-	sizes, err := s.GetAllSizes()
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
 	assert.NoError(t, err)
 	assert.ElementsMatch(t, []int{250, 500, 1000, 2000, 5000}, sizes)
 }
@@ -38,10 +42,10 @@ func TestAddSize(t *testing.T) {
 	assert.NoError(t, err)
 	defer s.Close()
 
-	err = s.AddSize(300)
+	err = s.AddSize(sizer.DefaultCatalog, 300)
 	assert.NoError(t, err)
 
-	sizes, err := s.GetAllSizes()
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
 	assert.NoError(t, err)
 	assert.Contains(t, sizes, 300)
 }
@@ -54,10 +58,10 @@ func TestRemoveSize(t *testing.T) {
 	assert.NoError(t, err)
 	defer s.Close()
 
-	err = s.RemoveSize(250)
+	err = s.RemoveSize(sizer.DefaultCatalog, 250)
 	assert.NoError(t, err)
 
-	sizes, err := s.GetAllSizes()
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
 	assert.NoError(t, err)
 	assert.NotContains(t, sizes, 250)
 }
@@ -70,7 +74,7 @@ func TestRemoveSize_NotFound(t *testing.T) {
 	assert.NoError(t, err)
 	defer s.Close()
 
-	err = s.RemoveSize(99999)
+	err = s.RemoveSize(sizer.DefaultCatalog, 99999)
 	assert.Error(t, err)
 	assert.Equal(t, "pack size not found", err.Error())
 }
@@ -85,3 +89,398 @@ func TestClose(t *testing.T) {
 	err = s.Close()
 	assert.NoError(t, err)
 }
+
+func TestAddPack(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	err = s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true, Price: 2.75})
+	assert.NoError(t, err)
+
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Enabled: true, Price: 2.75})
+	assert.Contains(t, packs, sizer.Pack{Size: 250, Enabled: true})
+
+	err = s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true})
+	assert.NoError(t, err)
+
+	packs, err = s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Enabled: true})
+}
+
+func TestRemoveSize_RemovesPrice(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.NoError(t, s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true, Price: 2.75}))
+	assert.NoError(t, s.RemoveSize(sizer.DefaultCatalog, 300))
+	assert.NoError(t, s.AddSize(sizer.DefaultCatalog, 300))
+
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Enabled: true})
+}
+
+func TestAddPack_Stock(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	stock := 12
+	assert.NoError(t, s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true, Stock: &stock}))
+
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Enabled: true, Stock: &stock})
+
+	assert.NoError(t, s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true}))
+
+	packs, err = s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Enabled: true})
+}
+
+func TestCatalogs_AreIndependent(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	sizes, err := s.GetAllSizes("bolts")
+	assert.NoError(t, err)
+	assert.Empty(t, sizes)
+
+	assert.NoError(t, s.AddPack("bolts", sizer.Pack{Size: 40, Enabled: true, Price: 2}))
+	assert.NoError(t, s.AddSize("bolts_2", 7))
+
+	packs, err := s.GetAllPacks("bolts")
+	assert.NoError(t, err)
+	assert.Equal(t, []sizer.Pack{{Size: 40, Enabled: true, Price: 2}}, packs)
+
+	sizes, err = s.GetAllSizes("bolts_2")
+	assert.NoError(t, err)
+	assert.Equal(t, []int{7}, sizes)
+
+	sizes, err = s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.NotContains(t, sizes, 40)
+
+	assert.Error(t, s.RemoveSize(sizer.DefaultCatalog, 40))
+	assert.NoError(t, s.RemoveSize("bolts", 40))
+}
+
+func TestCheckCatalog(t *testing.T) {
+	assert.NoError(t, sizer.CheckCatalog("bolts-M8_v2"))
+	for _, id := range []string{"", "a/b", "a b", strings.Repeat("x", 65)} {
+		assert.ErrorIs(t, sizer.CheckCatalog(id), sizer.ErrInvalidCatalog, id)
+	}
+
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.NoError(t, err)
+	defer s.Close()
+
+	assert.ErrorIs(t, s.AddSize("a/b", 1), sizer.ErrInvalidCatalog)
+}
+
+func TestNewSizer_MigratesLegacyLayout(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	db, err := leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("packs"), []byte("populated"), nil))
+	require.NoError(t, db.Put([]byte("size_300"), []byte("300"), nil))
+	require.NoError(t, db.Put([]byte("price_300"), []byte("2.5"), nil))
+	require.NoError(t, db.Put([]byte("stock_300"), []byte("4"), nil))
+	require.NoError(t, db.Put([]byte("size_600"), []byte(`{"size":600,"name":"Crate"}`), nil))
+	require.NoError(t, db.Put([]byte("catalog_bolts/size_40"), []byte("40"), nil))
+	require.NoError(t, db.Put([]byte("catalog_bolts/price_40"), []byte("1.5"), nil))
+	require.NoError(t, db.Close())
+
+	for i := 0; i < 2; i++ {
+		s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+		require.NoError(t, err)
+
+		stock := 4
+		packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+		assert.NoError(t, err)
+		assert.Equal(t, []sizer.Pack{
+			{Size: 600, Name: "Crate", Enabled: true},
+			{Size: 300, Enabled: true, Price: 2.5, Stock: &stock},
+		}, packs)
+
+		packs, err = s.GetAllPacks("bolts")
+		assert.NoError(t, err)
+		assert.Equal(t, []sizer.Pack{{Size: 40, Enabled: true, Price: 1.5}}, packs)
+
+		v, err := s.GetVersion("bolts", 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "initial version", v.Reason)
+		assert.Equal(t, packs, v.Packs)
+
+		require.NoError(t, s.Close())
+	}
+
+	db, err = leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	version, err := db.Get([]byte("schema_version"), nil)
+	assert.NoError(t, err)
+	assert.Equal(t, strconv.Itoa(sizer.SchemaVersion()), string(version))
+	for _, key := range []string{"packs", "size_300", "price_300", "stock_300", "size_600", "catalog_bolts/price_40"} {
+		has, err := db.Has([]byte(key), nil)
+		assert.NoError(t, err)
+		assert.False(t, has, key)
+	}
+}
+
+func TestNewSizer_RefusesNewerSchema(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	db, err := leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	require.NoError(t, db.Put([]byte("schema_version"), []byte(strconv.Itoa(sizer.SchemaVersion()+1)), nil))
+	require.NoError(t, db.Close())
+
+	_, err = sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	assert.ErrorIs(t, err, sizer.ErrSchemaTooNew)
+
+	db, err = leveldb.OpenFile(dir, nil)
+	require.NoError(t, err)
+	defer db.Close()
+
+	has, err := db.Has([]byte("packs"), nil)
+	assert.NoError(t, err)
+	assert.False(t, has)
+}
+
+func TestUpdatePack(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	pack := sizer.Pack{
+		Size:       300,
+		Code:       "BOX-300",
+		Name:       "Medium box",
+		Enabled:    true,
+		Weight:     1.2,
+		Dimensions: &sizer.Dimensions{Length: 40, Width: 30, Height: 20},
+		Labels:     map[string]string{"supplier": "acme"},
+	}
+	require.NoError(t, s.AddPack(sizer.DefaultCatalog, pack))
+
+	got, err := s.GetPack(sizer.DefaultCatalog, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, pack, got)
+
+	assert.NoError(t, s.UpdatePack(sizer.DefaultCatalog, 300, func(p *sizer.Pack) error {
+		p.Enabled = false
+		return nil
+	}))
+	got, err = s.GetPack(sizer.DefaultCatalog, 300)
+	assert.NoError(t, err)
+	assert.False(t, got.Enabled)
+	assert.Equal(t, "BOX-300", got.Code)
+
+	assert.ErrorIs(t, s.UpdatePack(sizer.DefaultCatalog, 301, func(p *sizer.Pack) error { return nil }), sizer.ErrSizeNotFound)
+	assert.Error(t, s.UpdatePack(sizer.DefaultCatalog, 300, func(p *sizer.Pack) error {
+		p.Size = 400
+		return nil
+	}))
+	assert.Error(t, s.UpdatePack(sizer.DefaultCatalog, 300, func(p *sizer.Pack) error {
+		p.Weight = -1
+		return nil
+	}))
+
+	got, err = s.GetPack(sizer.DefaultCatalog, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, 1.2, got.Weight)
+}
+
+func TestSetEnabled(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.AddPack(sizer.DefaultCatalog, sizer.Pack{Size: 300, Enabled: true, Price: 2.75}))
+
+	assert.NoError(t, s.SetEnabled(sizer.DefaultCatalog, 300, false))
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Contains(t, packs, sizer.Pack{Size: 300, Price: 2.75})
+
+	assert.NoError(t, s.SetEnabled(sizer.DefaultCatalog, 300, true))
+	pack, err := s.GetPack(sizer.DefaultCatalog, 300)
+	assert.NoError(t, err)
+	assert.Equal(t, sizer.Pack{Size: 300, Enabled: true, Price: 2.75}, pack)
+
+	assert.ErrorIs(t, s.SetEnabled(sizer.DefaultCatalog, 301, false), sizer.ErrSizeNotFound)
+}
+
+func TestReplaceSizes(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.NoError(t, s.ReplaceSizes(sizer.DefaultCatalog, []sizer.Pack{
+		sizer.NewPack(300),
+		{Size: 600, Enabled: true, Price: 4},
+		sizer.NewPack(1200),
+	}))
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []sizer.Pack{sizer.NewPack(1200), {Size: 600, Enabled: true, Price: 4}, sizer.NewPack(300)}, packs)
+
+	assert.Error(t, s.ReplaceSizes(sizer.DefaultCatalog, []sizer.Pack{sizer.NewPack(100), sizer.NewPack(100)}))
+	assert.Error(t, s.ReplaceSizes(sizer.DefaultCatalog, []sizer.Pack{sizer.NewPack(100), {Size: -1}}))
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{1200, 600, 300}, sizes)
+}
+
+func TestGetAllPacks_ConcurrentReplace(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	sets := [][]sizer.Pack{
+		{sizer.NewPack(300), sizer.NewPack(600)},
+		{sizer.NewPack(400), sizer.NewPack(800), sizer.NewPack(1600)},
+	}
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 100; i++ {
+			assert.NoError(t, s.ReplaceSizes(sizer.DefaultCatalog, sets[i%2]))
+		}
+	}()
+
+	// Every read sees one whole set, never a mix or a size that vanished while reading.
+	for {
+		select {
+		case <-done:
+			return
+		default:
+		}
+		packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+		require.NoError(t, err)
+		if len(packs) == 3 {
+			assert.Equal(t, 1600, packs[0].Size)
+		} else if len(packs) == 2 {
+			assert.Equal(t, 600, packs[0].Size)
+		}
+	}
+}
+
+func TestVersions(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	require.NoError(t, s.AddSize(sizer.DefaultCatalog, 300))
+	require.NoError(t, s.AddSize(sizer.DefaultCatalog, 300))
+	require.NoError(t, s.SetEnabled(sizer.DefaultCatalog, 250, false))
+	require.NoError(t, s.ReplaceSizes(sizer.DefaultCatalog, []sizer.Pack{sizer.NewPack(600)}))
+	assert.Error(t, s.RemoveSize(sizer.DefaultCatalog, 250))
+
+	versions, err := s.GetVersions(sizer.DefaultCatalog)
+	require.NoError(t, err)
+	require.Len(t, versions, 4)
+	for i, reason := range []string{"seed catalog", "add size 300", "disable size 250", "replace all sizes"} {
+		assert.Equal(t, i+1, versions[i].Number)
+		assert.Equal(t, reason, versions[i].Reason)
+		assert.False(t, versions[i].CreatedAt.IsZero())
+		assert.Nil(t, versions[i].Packs)
+	}
+
+	latest, err := s.LatestVersion(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, 4, latest)
+
+	v, err := s.GetVersion(sizer.DefaultCatalog, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []int{5000, 2000, 1000, 500, 300, 250}, sizesOf(v.Packs))
+
+	require.NoError(t, s.RestoreVersion(sizer.DefaultCatalog, 2))
+	packs, err := s.GetAllPacks(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, v.Packs, packs)
+
+	v, err = s.GetVersion(sizer.DefaultCatalog, 5)
+	assert.NoError(t, err)
+	assert.Equal(t, "restore version 2", v.Reason)
+
+	current, err := s.CurrentVersion(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, 5, current.Number)
+	assert.Equal(t, v.Packs, current.Packs)
+
+	_, err = s.GetVersion(sizer.DefaultCatalog, 6)
+	assert.ErrorIs(t, err, sizer.ErrVersionNotFound)
+	assert.ErrorIs(t, s.RestoreVersion(sizer.DefaultCatalog, 6), sizer.ErrVersionNotFound)
+
+	latest, err = s.LatestVersion("bolts")
+	assert.NoError(t, err)
+	assert.Equal(t, 0, latest)
+	current, err = s.CurrentVersion("bolts")
+	assert.NoError(t, err)
+	assert.Equal(t, sizer.Version{Packs: []sizer.Pack{}}, current)
+}
+
+func sizesOf(packs []sizer.Pack) []int {
+	sizes := make([]int, len(packs))
+	for i, pack := range packs {
+		sizes[i] = pack.Size
+	}
+	return sizes
+}
+
+func TestPack_Validate(t *testing.T) {
+	stock := -1
+	assert.NoError(t, sizer.NewPack(250).Validate())
+	assert.Error(t, sizer.Pack{}.Validate())
+	assert.Error(t, sizer.Pack{Size: 1, Price: -1}.Validate())
+	assert.Error(t, sizer.Pack{Size: 1, Stock: &stock}.Validate())
+	assert.Error(t, sizer.Pack{Size: 1, Weight: -1}.Validate())
+	assert.Error(t, sizer.Pack{Size: 1, Dimensions: &sizer.Dimensions{Height: -1}}.Validate())
+
+	var fieldErr *sizer.FieldError
+	require.ErrorAs(t, sizer.Pack{Size: 1, Price: -1}.Validate(), &fieldErr)
+	assert.Equal(t, "price", fieldErr.Field)
+}
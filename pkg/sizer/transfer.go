@@ -0,0 +1,473 @@
+package sizer
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Format is a file format the pack records of a catalog are exported to and imported
+// from.
+type Format string
+
+// Supported formats. JSON and YAML hold a list of pack records, or of bare sizes; CSV
+// holds one pack per row under a header naming the columns in csvColumns.
+const (
+	FormatJSON Format = "json"
+	FormatCSV  Format = "csv"
+	FormatYAML Format = "yaml"
+)
+
+// csvColumns are the columns of a CSV export. Imports accept them in any order and only
+// require size. Labels are written as key=value pairs separated by ';'.
+var csvColumns = []string{"size", "code", "name", "enabled", "price", "stock", "weight", "length", "width", "height", "labels"}
+
+// ErrUnknownFormat is returned by ParseFormat for a format that is not supported.
+var ErrUnknownFormat = errors.New("unknown format, expected json, csv or yaml")
+
+// ParseFormat returns the Format named v, case-insensitively. An empty name selects JSON.
+func ParseFormat(v string) (Format, error) {
+	switch strings.ToLower(v) {
+	case "", "json":
+		return FormatJSON, nil
+	case "csv":
+		return FormatCSV, nil
+	case "yaml", "yml":
+		return FormatYAML, nil
+	}
+	return "", ErrUnknownFormat
+}
+
+// LineError reports why the record starting on Line of an import, counted from 1, cannot
+// be stored.
+type LineError struct {
+	Line    int    `json:"line"`
+	Message string `json:"message"`
+}
+
+// Error implements the error interface.
+func (e LineError) Error() string {
+	return fmt.Sprintf("line %d: %s", e.Line, e.Message)
+}
+
+// ImportError lists every malformed record of an import.
+type ImportError struct {
+	Lines []LineError
+}
+
+// Error implements the error interface.
+func (e *ImportError) Error() string {
+	if len(e.Lines) == 1 {
+		return "invalid import: " + e.Lines[0].Error()
+	}
+	return fmt.Sprintf("invalid import: %d malformed records, first %v", len(e.Lines), e.Lines[0])
+}
+
+// Export writes packs to w in format f.
+func Export(w io.Writer, f Format, packs []Pack) error {
+	if packs == nil {
+		packs = []Pack{}
+	}
+
+	switch f {
+	case FormatJSON:
+		enc := json.NewEncoder(w)
+		enc.SetIndent("", "  ")
+		return enc.Encode(packs)
+	case FormatYAML:
+		enc := yaml.NewEncoder(w)
+		enc.SetIndent(2)
+		if err := enc.Encode(packs); err != nil {
+			return err
+		}
+		return enc.Close()
+	case FormatCSV:
+		cw := csv.NewWriter(w)
+		if err := cw.Write(csvColumns); err != nil {
+			return err
+		}
+		for _, pack := range packs {
+			if err := cw.Write(csvRow(pack)); err != nil {
+				return err
+			}
+		}
+		cw.Flush()
+		return cw.Error()
+	}
+	return ErrUnknownFormat
+}
+
+// ParseImport reads the pack records held by data in format f. Records without an
+// enabled field are enabled. It returns an *ImportError listing every record that is
+// malformed, invalid or repeats a size, or when data holds no record at all.
+func ParseImport(data []byte, f Format) ([]Pack, error) {
+	var (
+		packs []Pack
+		lines []LineError
+	)
+	switch f {
+	case FormatJSON:
+		packs, lines = parseJSON(data)
+	case FormatYAML:
+		packs, lines = parseYAML(data)
+	case FormatCSV:
+		packs, lines = parseCSV(data)
+	default:
+		return nil, ErrUnknownFormat
+	}
+
+	if len(lines) == 0 && len(packs) == 0 {
+		lines = append(lines, LineError{Line: 1, Message: "no packs found"})
+	}
+	if len(lines) > 0 {
+		return nil, &ImportError{Lines: lines}
+	}
+	return packs, nil
+}
+
+// ImportPlan describes what an import changes in a catalog. Sizes are sorted in
+// descending order.
+type ImportPlan struct {
+	Added     []int `json:"added"`
+	Updated   []int `json:"updated"`
+	Removed   []int `json:"removed"`
+	Unchanged int   `json:"unchanged"`
+}
+
+// PlanImport returns the changes importing packs makes to a catalog holding current.
+// Merging keeps the sizes packs leaves out; replacing removes them.
+func PlanImport(current, packs []Pack, replace bool) ImportPlan {
+	before := make(map[int][]byte, len(current))
+	for _, pack := range current {
+		before[pack.Size], _ = encodePack(pack)
+	}
+
+	plan := ImportPlan{Added: []int{}, Updated: []int{}, Removed: []int{}}
+	imported := make(map[int]bool, len(packs))
+	for _, pack := range packs {
+		imported[pack.Size] = true
+		old, ok := before[pack.Size]
+		value, _ := encodePack(pack)
+		switch {
+		case !ok:
+			plan.Added = append(plan.Added, pack.Size)
+		case !bytes.Equal(old, value):
+			plan.Updated = append(plan.Updated, pack.Size)
+		default:
+			plan.Unchanged++
+		}
+	}
+	for size := range before {
+		if imported[size] {
+			continue
+		}
+		if replace {
+			plan.Removed = append(plan.Removed, size)
+		} else {
+			plan.Unchanged++
+		}
+	}
+
+	for _, sizes := range [][]int{plan.Added, plan.Updated, plan.Removed} {
+		sort.Sort(sort.Reverse(sort.IntSlice(sizes)))
+	}
+	return plan
+}
+
+// records collects the packs of an import with the errors of their lines.
+type records struct {
+	packs []Pack
+	lines []LineError
+	seen  map[int]int
+}
+
+// add validates pack, read from line, and keeps it unless it is invalid or repeats a
+// size.
+func (r *records) add(line int, pack Pack) {
+	if r.seen == nil {
+		r.seen = make(map[int]int)
+	}
+	if err := pack.Validate(); err != nil {
+		r.fail(line, err.Error())
+		return
+	}
+	if first, ok := r.seen[pack.Size]; ok {
+		r.fail(line, fmt.Sprintf("pack size %d already given on line %d", pack.Size, first))
+		return
+	}
+	r.seen[pack.Size] = line
+	r.packs = append(r.packs, pack)
+}
+
+// fail records why the record on line cannot be imported.
+func (r *records) fail(line int, message string) {
+	r.lines = append(r.lines, LineError{Line: line, Message: message})
+}
+
+// parseJSON reads a JSON array of pack objects or bare sizes.
+func parseJSON(data []byte) ([]Pack, []LineError) {
+	var r records
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('[') {
+		r.fail(lineAt(data, skipSeparators(data, 0)), "expected an array of packs")
+		return nil, r.lines
+	}
+
+	for dec.More() {
+		line := lineAt(data, skipSeparators(data, int(dec.InputOffset())))
+		var raw json.RawMessage
+		if err := dec.Decode(&raw); err != nil {
+			r.fail(line, "malformed JSON: "+err.Error())
+			return nil, r.lines
+		}
+
+		pack := NewPack(0)
+		if err := json.Unmarshal(raw, &pack.Size); err != nil {
+			if err := json.Unmarshal(raw, &pack); err != nil {
+				r.fail(line, err.Error())
+				continue
+			}
+		}
+		r.add(line, pack)
+	}
+	if _, err := dec.Token(); err != nil {
+		r.fail(lineAt(data, len(data)), "malformed JSON: "+err.Error())
+	}
+	return r.packs, r.lines
+}
+
+// parseYAML reads a YAML sequence of pack mappings or bare sizes.
+func parseYAML(data []byte) ([]Pack, []LineError) {
+	var r records
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		r.fail(yamlErrorLine(err), "malformed YAML: "+err.Error())
+		return nil, r.lines
+	}
+	if len(doc.Content) == 0 {
+		return nil, nil
+	}
+	list := doc.Content[0]
+	if list.Kind != yaml.SequenceNode {
+		r.fail(list.Line, "expected a list of packs")
+		return nil, r.lines
+	}
+
+	for _, item := range list.Content {
+		pack := NewPack(0)
+		var err error
+		if item.Kind == yaml.ScalarNode {
+			err = item.Decode(&pack.Size)
+		} else {
+			err = item.Decode(&pack)
+		}
+		if err != nil {
+			r.fail(item.Line, err.Error())
+			continue
+		}
+		r.add(item.Line, pack)
+	}
+	return r.packs, r.lines
+}
+
+// parseCSV reads a header row naming the columns followed by one pack per row.
+func parseCSV(data []byte) ([]Pack, []LineError) {
+	var r records
+	cr := csv.NewReader(bytes.NewReader(data))
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if err == io.EOF {
+		return nil, nil
+	}
+	if err != nil {
+		r.fail(1, "malformed CSV: "+err.Error())
+		return nil, r.lines
+	}
+	columns := make(map[string]int, len(header))
+	for i, name := range header {
+		name = strings.ToLower(strings.TrimSpace(name))
+		if !isCSVColumn(name) {
+			r.fail(1, fmt.Sprintf("unknown column %q", name))
+			continue
+		}
+		columns[name] = i
+	}
+	if _, ok := columns["size"]; !ok {
+		r.fail(1, "missing size column")
+	}
+	if len(r.lines) > 0 {
+		return nil, r.lines
+	}
+
+	for {
+		row, err := cr.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			line := 0
+			var parseErr *csv.ParseError
+			if errors.As(err, &parseErr) {
+				line = parseErr.StartLine
+			}
+			r.fail(line, "malformed CSV: "+err.Error())
+			return nil, r.lines
+		}
+
+		line, _ := cr.FieldPos(0)
+		if len(row) != len(header) {
+			r.fail(line, fmt.Sprintf("expected %d fields, found %d", len(header), len(row)))
+			continue
+		}
+		pack, err := csvPack(row, columns)
+		if err != nil {
+			r.fail(line, err.Error())
+			continue
+		}
+		r.add(line, pack)
+	}
+	return r.packs, r.lines
+}
+
+// csvPack reads the pack held by row, whose columns are indexed by name. Empty cells
+// leave their field unset.
+func csvPack(row []string, columns map[string]int) (Pack, error) {
+	cell := func(name string) string {
+		if i, ok := columns[name]; ok {
+			return strings.TrimSpace(row[i])
+		}
+		return ""
+	}
+	number := func(name string, dst *float64) error {
+		v := cell(name)
+		if v == "" {
+			return nil
+		}
+		f, err := strconv.ParseFloat(v, 64)
+		if err != nil {
+			return fmt.Errorf("invalid %s %q", name, v)
+		}
+		*dst = f
+		return nil
+	}
+
+	pack := NewPack(0)
+	var err error
+	if pack.Size, err = strconv.Atoi(cell("size")); err != nil {
+		return Pack{}, fmt.Errorf("invalid size %q", cell("size"))
+	}
+	pack.Code = cell("code")
+	pack.Name = cell("name")
+	if v := cell("enabled"); v != "" {
+		if pack.Enabled, err = strconv.ParseBool(v); err != nil {
+			return Pack{}, fmt.Errorf("invalid enabled %q", v)
+		}
+	}
+	if v := cell("stock"); v != "" {
+		stock, err := strconv.Atoi(v)
+		if err != nil {
+			return Pack{}, fmt.Errorf("invalid stock %q", v)
+		}
+		pack.Stock = &stock
+	}
+	if err := number("price", &pack.Price); err != nil {
+		return Pack{}, err
+	}
+	if err := number("weight", &pack.Weight); err != nil {
+		return Pack{}, err
+	}
+	if cell("length") != "" || cell("width") != "" || cell("height") != "" {
+		pack.Dimensions = &Dimensions{}
+		for name, dst := range map[string]*float64{"length": &pack.Dimensions.Length, "width": &pack.Dimensions.Width, "height": &pack.Dimensions.Height} {
+			if err := number(name, dst); err != nil {
+				return Pack{}, err
+			}
+		}
+	}
+	if v := cell("labels"); v != "" {
+		pack.Labels = make(map[string]string)
+		for _, pair := range strings.Split(v, ";") {
+			key, value, ok := strings.Cut(pair, "=")
+			if !ok || strings.TrimSpace(key) == "" {
+				return Pack{}, fmt.Errorf("invalid label %q, expected key=value", pair)
+			}
+			pack.Labels[strings.TrimSpace(key)] = strings.TrimSpace(value)
+		}
+	}
+	return pack, nil
+}
+
+// csvRow returns the cells of pack in the order of csvColumns.
+func csvRow(pack Pack) []string {
+	number := func(v float64) string {
+		if v == 0 {
+			return ""
+		}
+		return strconv.FormatFloat(v, 'f', -1, 64)
+	}
+
+	row := []string{strconv.Itoa(pack.Size), pack.Code, pack.Name, strconv.FormatBool(pack.Enabled), number(pack.Price), "", number(pack.Weight), "", "", "", ""}
+	if pack.Stock != nil {
+		row[5] = strconv.Itoa(*pack.Stock)
+	}
+	if d := pack.Dimensions; d != nil {
+		row[7] = strconv.FormatFloat(d.Length, 'f', -1, 64)
+		row[8] = strconv.FormatFloat(d.Width, 'f', -1, 64)
+		row[9] = strconv.FormatFloat(d.Height, 'f', -1, 64)
+	}
+	if len(pack.Labels) > 0 {
+		pairs := make([]string, 0, len(pack.Labels))
+		for key, value := range pack.Labels {
+			pairs = append(pairs, key+"="+value)
+		}
+		sort.Strings(pairs)
+		row[10] = strings.Join(pairs, ";")
+	}
+	return row
+}
+
+// isCSVColumn reports whether name is one of csvColumns.
+func isCSVColumn(name string) bool {
+	for _, column := range csvColumns {
+		if name == column {
+			return true
+		}
+	}
+	return false
+}
+
+// skipSeparators returns the offset of the first byte of data from offset on that is
+// neither white space nor a comma.
+func skipSeparators(data []byte, offset int) int {
+	for offset < len(data) && strings.IndexByte(" \t\r\n,", data[offset]) >= 0 {
+		offset++
+	}
+	return offset
+}
+
+// lineAt returns the line, counted from 1, holding the byte at offset in data.
+func lineAt(data []byte, offset int) int {
+	if offset > len(data) {
+		offset = len(data)
+	}
+	return 1 + bytes.Count(data[:offset], []byte("\n"))
+}
+
+// yamlErrorLine returns the line a YAML syntax error points at, or 1 when it names none.
+func yamlErrorLine(err error) int {
+	var line int
+	if _, scanErr := fmt.Sscanf(err.Error(), "yaml: line %d:", &line); scanErr != nil || line <= 0 {
+		return 1
+	}
+	return line
+}
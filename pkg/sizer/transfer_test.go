@@ -0,0 +1,120 @@
+package sizer_test
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/logger"
+	"github.com/jmsilvadev/go-pack-optimizer/pkg/sizer"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap/zapcore"
+)
+
+func TestParseFormat(t *testing.T) {
+	for name, expected := range map[string]sizer.Format{"": sizer.FormatJSON, "JSON": sizer.FormatJSON, "csv": sizer.FormatCSV, "yml": sizer.FormatYAML} {
+		f, err := sizer.ParseFormat(name)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, f)
+	}
+
+	_, err := sizer.ParseFormat("xml")
+	assert.ErrorIs(t, err, sizer.ErrUnknownFormat)
+}
+
+func TestExportImport_RoundTrip(t *testing.T) {
+	stock := 12
+	packs := []sizer.Pack{
+		{
+			Size:       1000,
+			Code:       "BOX-1000",
+			Name:       "Large box",
+			Enabled:    true,
+			Price:      9.5,
+			Stock:      &stock,
+			Weight:     2.25,
+			Dimensions: &sizer.Dimensions{Length: 60, Width: 40, Height: 30},
+			Labels:     map[string]string{"supplier": "acme", "fragile": "no"},
+		},
+		{Size: 250},
+	}
+
+	for _, f := range []sizer.Format{sizer.FormatJSON, sizer.FormatCSV, sizer.FormatYAML} {
+		var buf bytes.Buffer
+		require.NoError(t, sizer.Export(&buf, f, packs), f)
+
+		imported, err := sizer.ParseImport(buf.Bytes(), f)
+		require.NoError(t, err, f)
+		assert.Equal(t, packs, imported, f)
+	}
+}
+
+func TestParseImport_Defaults(t *testing.T) {
+	inputs := map[sizer.Format]string{
+		sizer.FormatJSON: `[300, {"size": 600, "price": 4}]`,
+		sizer.FormatCSV:  "size,price\n300,\n600,4\n",
+		sizer.FormatYAML: "- 300\n- size: 600\n  price: 4\n",
+	}
+	for f, input := range inputs {
+		packs, err := sizer.ParseImport([]byte(input), f)
+		require.NoError(t, err, f)
+		assert.Equal(t, []sizer.Pack{sizer.NewPack(300), {Size: 600, Enabled: true, Price: 4}}, packs, f)
+	}
+}
+
+func TestParseImport_LineErrors(t *testing.T) {
+	tests := []struct {
+		format   sizer.Format
+		input    string
+		expected []int
+	}{
+		{format: sizer.FormatJSON, input: "[\n  300,\n  {\"size\": -1},\n  {\"size\": \"x\"},\n  300\n]", expected: []int{3, 4, 5}},
+		{format: sizer.FormatJSON, input: "{\"size\": 300}", expected: []int{1}},
+		{format: sizer.FormatJSON, input: "[\n  300,\n  {\"size\": 600,\n]", expected: []int{3}},
+		{format: sizer.FormatCSV, input: "size,stock\n300,1\nabc,2\n600,-1\n700\n", expected: []int{3, 4, 5}},
+		{format: sizer.FormatCSV, input: "size,colour\n300,red\n", expected: []int{1}},
+		{format: sizer.FormatCSV, input: "size,labels\n300,fragile\n", expected: []int{2}},
+		{format: sizer.FormatYAML, input: "- 300\n- size: 0\n- size: [1]\n", expected: []int{2, 3}},
+		{format: sizer.FormatYAML, input: "size: 300\n", expected: []int{1}},
+		{format: sizer.FormatYAML, input: "", expected: []int{1}},
+	}
+	for _, test := range tests {
+		_, err := sizer.ParseImport([]byte(test.input), test.format)
+
+		var importErr *sizer.ImportError
+		require.ErrorAs(t, err, &importErr, test.input)
+		lines := make([]int, len(importErr.Lines))
+		for i, line := range importErr.Lines {
+			lines[i] = line.Line
+		}
+		assert.Equal(t, test.expected, lines, test.input)
+	}
+}
+
+func TestPlanImport(t *testing.T) {
+	current := []sizer.Pack{sizer.NewPack(1000), sizer.NewPack(500), sizer.NewPack(250)}
+	packs := []sizer.Pack{sizer.NewPack(1200), {Size: 500, Enabled: true, Price: 3}, sizer.NewPack(250)}
+
+	assert.Equal(t, sizer.ImportPlan{Added: []int{1200}, Updated: []int{500}, Removed: []int{}, Unchanged: 2}, sizer.PlanImport(current, packs, false))
+	assert.Equal(t, sizer.ImportPlan{Added: []int{1200}, Updated: []int{500}, Removed: []int{1000}, Unchanged: 1}, sizer.PlanImport(current, packs, true))
+}
+
+func TestMergePacks(t *testing.T) {
+	dir, cleanup := setupTempDB(t)
+	defer cleanup()
+
+	s, err := sizer.NewSizer(dir, logger.New(zapcore.DebugLevel))
+	require.NoError(t, err)
+	defer s.Close()
+
+	assert.NoError(t, s.MergePacks(sizer.DefaultCatalog, []sizer.Pack{sizer.NewPack(300), {Size: 500, Enabled: true, Price: 3}}))
+	sizes, err := s.GetAllSizes(sizer.DefaultCatalog)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{5000, 2000, 1000, 500, 300, 250}, sizes)
+
+	pack, err := s.GetPack(sizer.DefaultCatalog, 500)
+	assert.NoError(t, err)
+	assert.Equal(t, float64(3), pack.Price)
+
+	assert.Error(t, s.MergePacks(sizer.DefaultCatalog, []sizer.Pack{sizer.NewPack(100), sizer.NewPack(100)}))
+}
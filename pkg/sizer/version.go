@@ -0,0 +1,172 @@
+package sizer
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/syndtr/goleveldb/leveldb"
+	"github.com/syndtr/goleveldb/leveldb/util"
+)
+
+// Key names of the catalog history, under the prefix of each catalog.
+const (
+	versionPrefix    = "version_"
+	latestVersionKey = "latest_version"
+)
+
+// ErrVersionNotFound is returned when a catalog has no version of the requested number.
+var ErrVersionNotFound = errors.New("catalog version not found")
+
+// Version is an immutable snapshot of the pack records of a catalog, taken after every
+// change. Numbers start at 1 and grow by one with each change to the catalog.
+type Version struct {
+	Number    int       `json:"version"`
+	CreatedAt time.Time `json:"created_at"`
+	Reason    string    `json:"reason"`
+	Packs     []Pack    `json:"packs,omitempty"`
+}
+
+// GetVersions returns every version of catalog, oldest first, without their packs.
+func (s *Sizer) GetVersions(catalog string) ([]Version, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return nil, err
+	}
+
+	iter := s.db.NewIterator(util.BytesPrefix([]byte(keyPrefix(catalog)+versionPrefix)), nil)
+	defer iter.Release()
+
+	var versions []Version
+	for iter.Next() {
+		var v Version
+		if err := json.Unmarshal(iter.Value(), &v); err != nil {
+			return nil, fmt.Errorf("invalid record for %s: %v", iter.Key(), err)
+		}
+		v.Packs = nil
+		versions = append(versions, v)
+	}
+
+	return versions, iter.Error()
+}
+
+// GetVersion returns version number of catalog with its packs, or ErrVersionNotFound.
+func (s *Sizer) GetVersion(catalog string, number int) (Version, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return Version{}, err
+	}
+
+	value, err := s.db.Get(versionKey(catalog, number), nil)
+	if err == leveldb.ErrNotFound {
+		return Version{}, ErrVersionNotFound
+	}
+	if err != nil {
+		return Version{}, err
+	}
+
+	var v Version
+	if err := json.Unmarshal(value, &v); err != nil {
+		return Version{}, fmt.Errorf("invalid record for version %d: %v", number, err)
+	}
+	return v, nil
+}
+
+// LatestVersion returns the number of the newest version of catalog, or 0 when the
+// catalog has never been changed.
+func (s *Sizer) LatestVersion(catalog string) (int, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return 0, err
+	}
+	return latestIn(s.db, catalog)
+}
+
+// CurrentVersion returns the current pack records of catalog, disabled ones included,
+// numbered as the newest version. Both are read from a single snapshot of the database,
+// so the number is always that of the returned records. CreatedAt and Reason are not set.
+func (s *Sizer) CurrentVersion(catalog string) (Version, error) {
+	if err := CheckCatalog(catalog); err != nil {
+		return Version{}, err
+	}
+
+	snap, err := s.db.GetSnapshot()
+	if err != nil {
+		return Version{}, err
+	}
+	defer snap.Release()
+
+	packs, err := packsIn(snap, catalog)
+	if err != nil {
+		return Version{}, err
+	}
+	latest, err := latestIn(snap, catalog)
+	if err != nil {
+		return Version{}, err
+	}
+	return Version{Number: latest, Packs: packs}, nil
+}
+
+// latestIn returns the number of the newest version of catalog in r, or 0 when there is
+// none.
+func latestIn(r reader, catalog string) (int, error) {
+	value, err := r.Get([]byte(keyPrefix(catalog)+latestVersionKey), nil)
+	if err == leveldb.ErrNotFound {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return strconv.Atoi(string(value))
+}
+
+// RestoreVersion makes the pack records of version number the current ones of catalog.
+// The restore is itself recorded as a new version, so history is never rewritten.
+func (s *Sizer) RestoreVersion(catalog string, number int) error {
+	v, err := s.GetVersion(catalog, number)
+	if err != nil {
+		return err
+	}
+	return s.change(catalog, fmt.Sprintf("restore version %d", number), func(packs map[int]Pack) error {
+		return replaceIn(packs, v.Packs)
+	})
+}
+
+// addVersion queues in batch the next version of catalog, holding packs and described
+// by reason. The caller must hold mu.
+func (s *Sizer) addVersion(batch *leveldb.Batch, catalog, reason string, packs map[int]Pack) error {
+	latest, err := s.LatestVersion(catalog)
+	if err != nil {
+		return err
+	}
+
+	v := Version{
+		Number:    latest + 1,
+		CreatedAt: time.Now().UTC(),
+		Reason:    reason,
+		Packs:     make([]Pack, 0, len(packs)),
+	}
+	for _, pack := range packs {
+		v.Packs = append(v.Packs, pack)
+	}
+	sort.Slice(v.Packs, func(i, j int) bool { return v.Packs[i].Size > v.Packs[j].Size })
+
+	return putVersion(batch, catalog, v)
+}
+
+// putVersion queues in batch the record of v as the newest version of catalog.
+func putVersion(batch *leveldb.Batch, catalog string, v Version) error {
+	value, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	batch.Put(versionKey(catalog, v.Number), value)
+	batch.Put([]byte(keyPrefix(catalog)+latestVersionKey), []byte(strconv.Itoa(v.Number)))
+	return nil
+}
+
+// versionKey returns the key holding version number of catalog. Numbers are zero padded
+// so that versions iterate in order.
+func versionKey(catalog string, number int) []byte {
+	return []byte(fmt.Sprintf("%s%s%010d", keyPrefix(catalog), versionPrefix, number))
+}